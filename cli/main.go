@@ -11,6 +11,7 @@ import (
 	"image/jpeg"
 	"image/png"
 	"io"
+	"io/ioutil"
 	"math"
 	"net/http"
 	"os"
@@ -157,22 +158,34 @@ func main() {
 	defer imageReader.Close()
 
 	if vips {
-		i, format, err := decodeVips(imageReader)
-		checkErr(err)
-		decodeDuration = time.Since(startTime)
-		if cropRectangle != image.ZR {
-			localStartTime := time.Now()
-			i2, err := cropVips(i, cropRectangle.Min.X, cropRectangle.Min.Y, cropRectangle.Max.X, cropRectangle.Max.Y)
+		var i *govips.VipsImage
+		var format string
+		if cropRectangle == image.ZR && resizePoint != image.ZP {
+			// Without a crop, decode and resize in one step so the loader can shrink while decoding.
+			i, format, err = thumbnailVips(imageReader, resizePoint.X, resizePoint.Y)
 			checkErr(err)
-			i.Free()
-			i = i2
-			cropDuration = time.Since(localStartTime)
-		}
-		if resizePoint != image.ZP {
-			localStartTime := time.Now()
-			i, err = resizeVips(i, resizePoint.X, resizePoint.Y, fastResize)
+			resizeDuration = time.Since(startTime)
+		} else {
+			i, format, err = govips.DecodeStream(imageReader, &govips.DecodeFormatOptions{
+				Gif:  &govips.DecodeGifOptions{N: govips.ALL_PAGES},
+				Webp: &govips.DecodeWebpOptions{N: govips.ALL_PAGES},
+			})
 			checkErr(err)
-			resizeDuration = time.Since(localStartTime)
+			decodeDuration = time.Since(startTime)
+			if cropRectangle != image.ZR {
+				localStartTime := time.Now()
+				i2, err := cropVips(i, cropRectangle.Min.X, cropRectangle.Min.Y, cropRectangle.Max.X, cropRectangle.Max.Y)
+				checkErr(err)
+				i.Free()
+				i = i2
+				cropDuration = time.Since(localStartTime)
+			}
+			if resizePoint != image.ZP {
+				localStartTime := time.Now()
+				i, err = resizeVips(i, resizePoint.X, resizePoint.Y, fastResize)
+				checkErr(err)
+				resizeDuration = time.Since(localStartTime)
+			}
 		}
 		if blur > 0 {
 			localStartTime := time.Now()
@@ -183,16 +196,7 @@ func main() {
 			blurDuration = time.Since(localStartTime)
 		}
 		localStartTime := time.Now()
-		switch format {
-		case "jpeg":
-			err = govips.EncodeJpegFile(i, output, &govips.EncodeJpegOptions{Q: quality, OptimizeCoding: true, Strip: true, NoSubsample: true})
-		case "gif", "png":
-			err = govips.EncodePngFile(i, output, &govips.EncodePngOptions{Compression: 6})
-		case "webp":
-			err = govips.EncodeWebpFile(i, output, &govips.EncodeWebpOptions{Q: quality})
-		default:
-			err = fmt.Errorf("Invalid image format: %s\n", format)
-		}
+		err = encodeVips(i, format, output)
 		encodeDuration = time.Since(localStartTime)
 		checkErr(err)
 		i.Free()
@@ -238,62 +242,67 @@ func main() {
 	fmt.Printf("  Total: %v\n", totalDuration)
 }
 
-func decodeVips(reader io.ReadSeeker) (*govips.VipsImage, string, error) {
-	_, format, err := image.DecodeConfig(reader)
+func frameHeightVips(i *govips.VipsImage) int {
+	if pageHeight := i.Animation().PageHeight; pageHeight > 0 {
+		return pageHeight
+	}
+	return i.Bounds().Dy()
+}
+
+func thumbnailVips(r io.Reader, width, height int) (*govips.VipsImage, string, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+	format, err := govips.DetectFormatBytes(b)
 	if err != nil {
 		return nil, format, err
 	}
-	reader.Seek(0, 0)
-	switch format {
-	case "gif":
-		vipsImage, err := govips.DecodeMagickReader(reader, nil)
-		return vipsImage, format, err
-	case "jpeg":
-		vipsImage, err := govips.DecodeJpegReader(reader, nil)
-		return vipsImage, format, err
-	case "png":
-		vipsImage, err := govips.DecodePngReader(reader, nil)
-		return vipsImage, format, err
-	case "webp":
-		vipsImage, err := govips.DecodeWebpReader(reader, nil)
-		return vipsImage, format, err
+	options := govips.ThumbnailOptions{Size: govips.VIPS_SIZE_DOWN}
+	if format == govips.FORMAT_GIF || format == govips.FORMAT_WEBP {
+		options.OptionString = "n=-1"
 	}
-	return nil, format, fmt.Errorf("Unhandled format: %s", format)
+	i, err := govips.Thumbnail(b, width, height, &options)
+	return i, format, err
 }
 
 func resizeVips(i *govips.VipsImage, width, height int, useFastScale bool) (*govips.VipsImage, error) {
-	scale := math.Min(float64(width)/float64(i.Bounds().Dx()), float64(height)/float64(i.Bounds().Dy()))
-	if scale < 1 {
-		//i2, err := govips.Resize(i, scale, scale, govips.VIPS_KERNEL_LANCZOS3)
-		//checkErr(err)
-		//i.Free()
-		//i = i2
-		//return i, nil
-
-		// Perform a fast shrink ...
-		if useFastScale {
-			shrink := math.Max(1, math.Floor(1/(scale*2)))
-			if shrink > 1 {
-				i2, err := govips.Shrink(i, shrink, shrink)
-				checkErr(err)
-				i.Free()
-				i = i2
-			}
-		}
-		// Recompute scale...
-		scale = math.Min(float64(width)/float64(i.Bounds().Dx()), float64(height)/float64(i.Bounds().Dy()))
-		if scale < 1 {
-			i2, err := govips.Reduce(i, 1/scale, 1/scale, govips.VIPS_KERNEL_LANCZOS3)
+	scale := math.Min(float64(width)/float64(i.Bounds().Dx()), float64(height)/float64(frameHeightVips(i)))
+	// Perform a fast shrink ...
+	if useFastScale && scale < 1 {
+		shrink := math.Max(1, math.Floor(1/(scale*2)))
+		if shrink > 1 {
+			i2, err := govips.MapFrames(i, func(frame *govips.VipsImage) (*govips.VipsImage, error) {
+				return govips.Shrink(frame, shrink, shrink)
+			})
 			checkErr(err)
 			i.Free()
 			i = i2
 		}
 	}
-	return i, nil
+	i2, err := govips.ResizeTo(i, width, height, &govips.ResizeOptions{WithoutEnlargement: true})
+	checkErr(err)
+	i.Free()
+	return i2, nil
+}
+
+func encodeVips(i *govips.VipsImage, format string, output *os.File) error {
+	switch format {
+	case "jpeg":
+		return govips.EncodeJpegFile(i, output, &govips.EncodeJpegOptions{Q: quality, OptimizeCoding: true, Strip: true, NoSubsample: true})
+	case "gif":
+		return govips.EncodeGifFile(i, output, nil)
+	case "png":
+		return govips.EncodePngFile(i, output, &govips.EncodePngOptions{Compression: 6})
+	case "webp":
+		return govips.EncodeWebpFile(i, output, &govips.EncodeWebpOptions{Q: quality})
+	default:
+		return fmt.Errorf("Invalid image format: %s\n", format)
+	}
 }
 
 func cropVips(i *govips.VipsImage, x, y, width, height int) (*govips.VipsImage, error) {
-	return govips.Crop(i, x, y, width, height)
+	return govips.ExtractAreaFrames(i, x, y, width, height)
 }
 
 func resizeNative(i image.Image, width, height int, scaler draw.Scaler, useFastScale bool) image.Image {
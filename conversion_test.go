@@ -40,6 +40,35 @@ func Test_ExtractArea(t *testing.T) {
 	}
 }
 
+func Test_ExtractAreaMultiPage(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	options := DecodePdfOptions{N: ALL_PAGES}
+	vi := test_DecodePdfVips(t, "benchmark_images/72x48x2_pages.pdf", image.Rect(0, 0, 72, 96), &options)
+	defer vi.Free()
+	// Only the Frames variants work per page, the plain operations still see a single 72x96 strip.
+	vi2, err := ExtractArea(vi, 0, 24, 72, 48)
+	checkError(t, err)
+	defer vi2.Free()
+	if image.Rect(0, 0, 72, 48) != vi2.Bounds() {
+		t.Fatalf("Invalid bounds: %v", vi2.Bounds())
+	}
+	vi3, err := Embed(vi, 0, 0, 72, 120, nil)
+	checkError(t, err)
+	defer vi3.Free()
+	if image.Rect(0, 0, 72, 120) != vi3.Bounds() {
+		t.Fatalf("Invalid bounds: %v", vi3.Bounds())
+	}
+	vi4, err := EmbedFrames(vi, 0, 0, 72, 60, nil)
+	checkError(t, err)
+	defer vi4.Free()
+	if image.Rect(0, 0, 72, 120) != vi4.Bounds() || vi4.Animation().PageHeight != 60 {
+		t.Fatalf("Invalid bounds: %v", vi4.Bounds())
+	}
+}
+
 func Test_Crop(t *testing.T) {
 	err := Initialize()
 	defer ThreadShutdown()
@@ -55,6 +84,129 @@ func Test_Crop(t *testing.T) {
 	}
 }
 
+func Test_CropGravity(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	vi := test_DecodeJpegVips(t, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	defer vi.Free()
+	tests := map[Gravity]image.Point{
+		GravityCentre:    image.Pt(2104, 1578),
+		GravityNorth:     image.Pt(2104, 0),
+		GravityNorthEast: image.Pt(4208, 0),
+		GravityEast:      image.Pt(4208, 1578),
+		GravitySouthEast: image.Pt(4208, 3156),
+		GravitySouth:     image.Pt(2104, 3156),
+		GravitySouthWest: image.Pt(0, 3156),
+		GravityWest:      image.Pt(0, 1578),
+		GravityNorthWest: image.Pt(0, 0),
+	}
+	for gravity, min := range tests {
+		vi2, r, err := CropGravity(vi, 400, 300, gravity)
+		checkError(t, err)
+		defer vi2.Free()
+		if image.Rect(0, 0, 400, 300) != vi2.Bounds() {
+			t.Fatalf("Invalid bounds for gravity %v: %v", gravity, vi2.Bounds())
+		}
+		if (image.Rectangle{min, min.Add(image.Pt(400, 300))}) != r {
+			t.Fatalf("Invalid rectangle for gravity %v: %v", gravity, r)
+		}
+	}
+	if _, _, err := CropGravity(vi, 0, 300, GravityCentre); err != ErrCrop {
+		t.Fatalf("Expected %v, got %v", ErrCrop, err)
+	}
+}
+
+func Test_SmartCrop(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	vi := test_DecodeJpegVips(t, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	defer vi.Free()
+	tests := map[VipsInteresting]image.Rectangle{
+		VIPS_INTERESTING_CENTRE:    image.Rect(2104, 1578, 2504, 1878),
+		VIPS_INTERESTING_LOW:       image.Rect(0, 0, 400, 300),
+		VIPS_INTERESTING_HIGH:      image.Rect(4208, 3156, 4608, 3456),
+		VIPS_INTERESTING_ENTROPY:   image.ZR,
+		VIPS_INTERESTING_ATTENTION: image.ZR,
+	}
+	for interesting, expected := range tests {
+		vi2, r, err := SmartCrop(vi, 400, 300, interesting)
+		checkError(t, err)
+		defer vi2.Free()
+		if image.Rect(0, 0, 400, 300) != vi2.Bounds() {
+			t.Fatalf("Invalid bounds for %v: %v", interesting, vi2.Bounds())
+		}
+		if r.Size() != image.Pt(400, 300) || !r.In(BENCHMARK_IMAGE_1_BOUNDS) || (expected != image.ZR && expected != r) {
+			t.Fatalf("Invalid rectangle for %v: %v", interesting, r)
+		}
+	}
+}
+
+func Test_SmartCropOffCentre(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	// A flat image with a detailed subject in the bottom right, well away from the centre crop at 60,40.
+	m := image.NewGray(image.Rect(0, 0, 160, 120))
+	subject := image.Rect(100, 70, 140, 110)
+	for y := 0; y < 120; y++ {
+		for x := 0; x < 160; x++ {
+			m.SetGray(x, y, color.Gray{128})
+			if (image.Point{x, y}).In(subject) && (x/4+y/4)%2 == 0 {
+				m.SetGray(x, y, color.Gray{uint8(x * y % 256)})
+			}
+		}
+	}
+	vi, err := FromImage(m)
+	checkError(t, err)
+	defer vi.Free()
+	for _, interesting := range []VipsInteresting{VIPS_INTERESTING_ENTROPY, VIPS_INTERESTING_ATTENTION} {
+		vi2, r, err := SmartCrop(vi, 40, 40, interesting)
+		checkError(t, err)
+		defer vi2.Free()
+		if r.Size() != image.Pt(40, 40) || r.Min.X <= 60 || r.Min.Y <= 40 || !r.Overlaps(subject) {
+			t.Fatalf("Invalid rectangle for %v: %v", interesting, r)
+		}
+		gray, err := vi2.ToGray()
+		checkError(t, err)
+		for y := 0; y < 40; y++ {
+			for x := 0; x < 40; x++ {
+				if gray.GrayAt(x, y) != m.GrayAt(r.Min.X+x, r.Min.Y+y) {
+					t.Fatalf("Invalid color for %v at %d,%d: %v", interesting, x, y, gray.GrayAt(x, y))
+				}
+			}
+		}
+	}
+	if _, _, err := SmartCrop(vi, 40, -1, VIPS_INTERESTING_ENTROPY); err != ErrCrop {
+		t.Fatalf("Expected %v, got %v", ErrCrop, err)
+	}
+}
+
+func Test_SmartCropAnimated(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	options := DecodeGifOptions{N: ALL_PAGES}
+	bounds := image.Rect(0, 0, ANIMATED_IMAGE_FRAME_BOUNDS.Dx(), ANIMATED_IMAGE_FRAME_BOUNDS.Dy()*ANIMATED_IMAGE_FRAMES)
+	vi := test_DecodeGifVips(t, "benchmark_images/32x24x3_animated.gif", bounds, &options)
+	defer vi.Free()
+	vi2, r, err := SmartCrop(vi, 16, 16, VIPS_INTERESTING_ATTENTION)
+	checkError(t, err)
+	defer vi2.Free()
+	if !r.In(ANIMATED_IMAGE_FRAME_BOUNDS) {
+		t.Fatalf("Invalid rectangle: %v", r)
+	}
+	if image.Pt(16, 16*ANIMATED_IMAGE_FRAMES) != vi2.Bounds().Size() {
+		t.Fatalf("Invalid bounds: %v", vi2.Bounds())
+	}
+	checkAnimation(t, vi2, 16, []int{100, 200, 300})
+}
+
 func Test_Flatten(t *testing.T) {
 	err := Initialize()
 	defer ThreadShutdown()
@@ -89,3 +241,197 @@ func Test_Flatten(t *testing.T) {
 	runTest(color.NRGBA{0, 255, 0, 255}, &FlattenOptions{Background: []float64{0, 255, 0}})
 	runTest(color.NRGBA{0, 0, 255, 255}, &FlattenOptions{Background: []float64{0, 0, 255}})
 }
+
+func Test_Cast(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	vi := test_DecodePngVips(t, "benchmark_images/2x1_transparent_red.png", image.Rect(0, 0, 2, 1), nil)
+	defer vi.Free()
+	if vi.Format() != VIPS_FORMAT_UCHAR {
+		t.Fatalf("Invalid band format: %v", vi.Format())
+	}
+	tests := []struct {
+		format   VipsBandFormat
+		shift    bool
+		expected float64
+	}{
+		{VIPS_FORMAT_USHORT, false, 255},
+		{VIPS_FORMAT_USHORT, true, 65280},
+		{VIPS_FORMAT_FLOAT, false, 255},
+		{VIPS_FORMAT_CHAR, false, 127},
+	}
+	for _, test := range tests {
+		vi2, err := Cast(vi, test.format, test.shift)
+		checkError(t, err)
+		defer vi2.Free()
+		if vi2.Format() != test.format {
+			t.Fatalf("Invalid band format: %v", vi2.Format())
+		}
+		sample, err := NewSampleVipsImage(vi2)
+		checkError(t, err)
+		if sample.Sample(1, 0, 0) != test.expected {
+			t.Fatalf("Invalid sample for %v: %v", test.format, sample.Sample(1, 0, 0))
+		}
+	}
+}
+
+func Test_Autorotate(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	m := image.NewGray(image.Rect(0, 0, 4, 2))
+	m.SetGray(0, 0, color.Gray{255})
+	vi0, err := FromImage(m)
+	checkError(t, err)
+	defer vi0.Free()
+	vi, err := vi0.SetInt("orientation", 6)
+	checkError(t, err)
+	defer vi.Free()
+	if vi.Orientation() != 6 {
+		t.Fatalf("Invalid orientation: %d", vi.Orientation())
+	}
+	vi2, err := Autorotate(vi)
+	checkError(t, err)
+	defer vi2.Free()
+	if image.Rect(0, 0, 2, 4) != vi2.Bounds() {
+		t.Fatalf("Invalid bounds: %v", vi2.Bounds())
+	}
+	if vi2.Orientation() != 1 {
+		t.Fatalf("Invalid orientation: %d", vi2.Orientation())
+	}
+	gray, err := vi2.ToGray()
+	checkError(t, err)
+	if gray.GrayAt(1, 0) != (color.Gray{255}) {
+		t.Fatalf("Invalid color: %v", gray.GrayAt(1, 0))
+	}
+}
+
+func Test_DecodeAutorotate(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	vi0, err := FromImage(image.NewGray(image.Rect(0, 0, 16, 8)))
+	checkError(t, err)
+	defer vi0.Free()
+	vi, err := vi0.SetInt("orientation", 8)
+	checkError(t, err)
+	defer vi.Free()
+	b, err := EncodeJpegBytes(vi, nil)
+	checkError(t, err)
+	vi2, err := DecodeJpegBytes(b, &DecodeJpegOptions{DecodeOptions: DecodeOptions{Autorotate: true}})
+	checkError(t, err)
+	defer vi2.Free()
+	if image.Rect(0, 0, 8, 16) != vi2.Bounds() || vi2.Orientation() != 1 || vi2.HasField(EXIF_IFD0_PREFIX+"Orientation") {
+		t.Fatalf("Invalid autorotated image: %v, %d", vi2.Bounds(), vi2.Orientation())
+	}
+	vi5, err := DecodeJpegBytes(b, &DecodeJpegOptions{Autorotate: true})
+	checkError(t, err)
+	defer vi5.Free()
+	if image.Rect(0, 0, 8, 16) != vi5.Bounds() || vi5.Orientation() != 1 {
+		t.Fatalf("Invalid image autorotated by the JPEG option: %v, %d", vi5.Bounds(), vi5.Orientation())
+	}
+	tiff, err := EncodeTiffBytes(vi, nil)
+	checkError(t, err)
+	vi4, err := DecodeTiffBytes(tiff, &DecodeTiffOptions{DecodeOptions: DecodeOptions{Autorotate: true}})
+	checkError(t, err)
+	defer vi4.Free()
+	if image.Rect(0, 0, 8, 16) != vi4.Bounds() || vi4.Orientation() != 1 {
+		t.Fatalf("Invalid autorotated TIFF: %v, %d", vi4.Bounds(), vi4.Orientation())
+	}
+	vi3, _, err := DecodeBytes(b, nil)
+	checkError(t, err)
+	defer vi3.Free()
+	if image.Rect(0, 0, 16, 8) != vi3.Bounds() || vi3.Orientation() != 8 {
+		t.Fatalf("Invalid image: %v, %d", vi3.Bounds(), vi3.Orientation())
+	}
+}
+
+func Test_Rotate(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	m := image.NewGray(image.Rect(0, 0, 4, 2))
+	m.SetGray(0, 0, color.Gray{255})
+	vi, err := FromImage(m)
+	checkError(t, err)
+	defer vi.Free()
+	tests := map[VipsAngle]struct {
+		bounds image.Rectangle
+		white  image.Point
+	}{
+		VIPS_ANGLE_D0:   {image.Rect(0, 0, 4, 2), image.Pt(0, 0)},
+		VIPS_ANGLE_D90:  {image.Rect(0, 0, 2, 4), image.Pt(1, 0)},
+		VIPS_ANGLE_D180: {image.Rect(0, 0, 4, 2), image.Pt(3, 1)},
+		VIPS_ANGLE_D270: {image.Rect(0, 0, 2, 4), image.Pt(0, 3)},
+	}
+	for angle, expected := range tests {
+		vi2, err := Rotate(vi, angle)
+		checkError(t, err)
+		defer vi2.Free()
+		gray, err := vi2.ToGray()
+		checkError(t, err)
+		if expected.bounds != gray.Bounds() || gray.GrayAt(expected.white.X, expected.white.Y) != (color.Gray{255}) {
+			t.Fatalf("Invalid rotation for %v: %v", angle, gray)
+		}
+	}
+}
+
+func Test_Flip(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	m := image.NewGray(image.Rect(0, 0, 4, 2))
+	m.SetGray(0, 0, color.Gray{255})
+	vi, err := FromImage(m)
+	checkError(t, err)
+	defer vi.Free()
+	tests := map[VipsDirection]image.Point{
+		VIPS_DIRECTION_HORIZONTAL: image.Pt(3, 0),
+		VIPS_DIRECTION_VERTICAL:   image.Pt(0, 1),
+	}
+	for direction, white := range tests {
+		vi2, err := Flip(vi, direction)
+		checkError(t, err)
+		defer vi2.Free()
+		gray, err := vi2.ToGray()
+		checkError(t, err)
+		if gray.GrayAt(white.X, white.Y) != (color.Gray{255}) {
+			t.Fatalf("Invalid flip for %v: %v", direction, gray.Pix)
+		}
+	}
+}
+
+func Test_RotateDegrees(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	m := image.NewGray(image.Rect(0, 0, 20, 20))
+	for i := range m.Pix {
+		m.Pix[i] = 255
+	}
+	vi, err := FromImage(m)
+	checkError(t, err)
+	defer vi.Free()
+	vi2, err := RotateDegrees(vi, 45, &RotateOptions{Background: []float64{0}})
+	checkError(t, err)
+	defer vi2.Free()
+	if vi2.Bounds().Dx() <= 20 || vi2.Bounds().Dy() <= 20 {
+		t.Fatalf("Invalid bounds: %v", vi2.Bounds())
+	}
+	gray, err := vi2.ToGray()
+	checkError(t, err)
+	if gray.GrayAt(0, 0) != (color.Gray{0}) {
+		t.Fatalf("Invalid background: %v", gray.GrayAt(0, 0))
+	}
+	center := gray.Bounds().Size().Div(2)
+	if gray.GrayAt(center.X, center.Y) != (color.Gray{255}) {
+		t.Fatalf("Invalid color: %v", gray.GrayAt(center.X, center.Y))
+	}
+}
@@ -1,12 +1,15 @@
 package govips
 
 import (
+	"bytes"
 	_ "golang.org/x/image/webp"
 	"image"
+	"image/color"
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
 	"io"
+	"io/ioutil"
 	"os"
 	"sync/atomic"
 	"testing"
@@ -14,6 +17,10 @@ import (
 
 var BENCHMARK_IMAGE_1_BOUNDS = image.Rect(0, 0, 4608, 3456)
 
+var ANIMATED_IMAGE_FRAME_BOUNDS = image.Rect(0, 0, 32, 24)
+
+const ANIMATED_IMAGE_FRAMES = 3
+
 func Test_DecodeGifNative(t *testing.T) {
 	decodeNative(t, "benchmark_images/1.gif", BENCHMARK_IMAGE_1_BOUNDS)
 }
@@ -36,11 +43,71 @@ func Test_DecodeGifVips(t *testing.T) {
 	test_DecodeGifVips(t, "benchmark_images/1.gif", BENCHMARK_IMAGE_1_BOUNDS, &options).Free()
 }
 
+func Test_DecodeGifVipsAnimated(t *testing.T) {
+	options := DecodeGifOptions{N: ALL_PAGES}
+	bounds := image.Rect(0, 0, ANIMATED_IMAGE_FRAME_BOUNDS.Dx(), ANIMATED_IMAGE_FRAME_BOUNDS.Dy()*ANIMATED_IMAGE_FRAMES)
+	vi := test_DecodeGifVips(t, "benchmark_images/32x24x3_animated.gif", bounds, &options)
+	defer vi.Free()
+	checkAnimation(t, vi, ANIMATED_IMAGE_FRAME_BOUNDS.Dy(), []int{100, 200, 300})
+}
+
 func Test_DecodeJpegVips(t *testing.T) {
 	options := DecodeJpegOptions{DecodeOptions: DecodeOptions{Access: VIPS_ACCESS_SEQUENTIAL}}
 	test_DecodeJpegVips(t, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, &options).Free()
 }
 
+func Test_DecodeJpegVipsUnseekable(t *testing.T) {
+	test_DecodeVips(t, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, func(imageReader io.Reader) (*VipsImage, error) {
+		return DecodeJpegReader(struct{ io.Reader }{imageReader}, nil)
+	}).Free()
+}
+
+func Test_DecodeJpegVipsClosedReader(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	vi := test_DecodeJpegVips(t, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	defer vi.Free()
+	// The reader is closed by now, so the pixels must not depend on it.
+	_, err = EncodeJpegBytes(vi, nil)
+	checkError(t, err)
+}
+
+func Test_DecodeJpegVipsStream(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	imageReader, err := os.Open("benchmark_images/1.jpg")
+	checkError(t, err)
+	defer imageReader.Close()
+	vi, err := DecodeJpegStream(struct{ io.Reader }{imageReader}, &DecodeJpegOptions{DecodeOptions: DecodeOptions{Access: VIPS_ACCESS_SEQUENTIAL}})
+	checkError(t, err)
+	defer vi.Free()
+	if BENCHMARK_IMAGE_1_BOUNDS != vi.Bounds() {
+		t.Fatalf("Invalid bounds: %v", vi.Bounds())
+	}
+	_, err = EncodeJpegBytes(vi, nil)
+	checkError(t, err)
+}
+
+func Test_DecodeStream(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	imageReader, err := os.Open("benchmark_images/1.png")
+	checkError(t, err)
+	defer imageReader.Close()
+	vi, format, err := DecodeStream(imageReader, nil)
+	checkError(t, err)
+	defer vi.Free()
+	if format != FORMAT_PNG || BENCHMARK_IMAGE_1_BOUNDS != vi.Bounds() {
+		t.Fatalf("Invalid image: %s %v", format, vi.Bounds())
+	}
+}
+
 func Test_DecodeJpegVipsWithShrink(t *testing.T) {
 	options := DecodeJpegOptions{Shrink: 2, DecodeOptions: DecodeOptions{Access: VIPS_ACCESS_SEQUENTIAL}}
 	test_DecodeJpegVips(t, "benchmark_images/1.jpg", image.Rect(0, 0, BENCHMARK_IMAGE_1_BOUNDS.Dx()/2, BENCHMARK_IMAGE_1_BOUNDS.Dy()/2), &options).Free()
@@ -56,6 +123,252 @@ func Test_DecodeWebpVips(t *testing.T) {
 	test_DecodeWebpVips(t, "benchmark_images/1.webp", BENCHMARK_IMAGE_1_BOUNDS, &options).Free()
 }
 
+func Test_DecodePdfVips(t *testing.T) {
+	options := DecodePdfOptions{}
+	vi := test_DecodePdfVips(t, "benchmark_images/72x48x2_pages.pdf", image.Rect(0, 0, 72, 48), &options)
+	defer vi.Free()
+	if vi.Pages() != 2 {
+		t.Fatalf("Invalid page count: %d", vi.Pages())
+	}
+}
+
+func Test_DecodePdfVipsWithPages(t *testing.T) {
+	options := DecodePdfOptions{N: ALL_PAGES, Dpi: 144}
+	vi := test_DecodePdfVips(t, "benchmark_images/72x48x2_pages.pdf", image.Rect(0, 0, 144, 192), &options)
+	defer vi.Free()
+	if vi.Animation().PageHeight != 96 {
+		t.Fatalf("Invalid page height: %d", vi.Animation().PageHeight)
+	}
+}
+
+func Test_DecodePdfVipsWithPage(t *testing.T) {
+	options := DecodePdfOptions{Page: 1, Scale: 0.5, Background: []float64{0, 0, 0, 255}}
+	vi := test_DecodePdfVips(t, "benchmark_images/72x48x2_pages.pdf", image.Rect(0, 0, 36, 24), &options)
+	defer vi.Free()
+	if vi.Pages() != 2 {
+		t.Fatalf("Invalid page count: %d", vi.Pages())
+	}
+}
+
+func Test_DecodeSvgVips(t *testing.T) {
+	options := DecodeSvgOptions{}
+	test_DecodeSvgVips(t, "benchmark_images/64x48_logo.svg", image.Rect(0, 0, 64, 48), &options).Free()
+}
+
+func Test_DecodeSvgVipsWithDpi(t *testing.T) {
+	options := DecodeSvgOptions{Dpi: 144}
+	test_DecodeSvgVips(t, "benchmark_images/64x48_logo.svg", image.Rect(0, 0, 128, 96), &options).Free()
+}
+
+func Test_DecodeSvgVipsWithScale(t *testing.T) {
+	options := DecodeSvgOptions{Scale: 0.5}
+	test_DecodeSvgVips(t, "benchmark_images/64x48_logo.svg", image.Rect(0, 0, 32, 24), &options).Free()
+}
+
+func Test_DecodeSvgAnyVips(t *testing.T) {
+	options := DecodeFormatOptions{Svg: &DecodeSvgOptions{Scale: 2}}
+	vi, format := test_DecodeAnyVips(t, "benchmark_images/64x48_logo.svg", image.Rect(0, 0, 128, 96), &options)
+	defer vi.Free()
+	if format != FORMAT_SVG {
+		t.Fatalf("Invalid format: %s", format)
+	}
+}
+
+func Test_DecodeTiffVips(t *testing.T) {
+	vi := test_DecodeJpegVips(t, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	defer vi.Free()
+	b, err := EncodeTiffBytes(vi, &EncodeTiffOptions{Compression: VIPS_TIFF_COMPRESSION_LZW})
+	checkError(t, err)
+	vi2, err := DecodeTiffReader(bytes.NewReader(b), &DecodeTiffOptions{DecodeOptions: DecodeOptions{Access: VIPS_ACCESS_SEQUENTIAL}})
+	checkError(t, err)
+	defer vi2.Free()
+	if BENCHMARK_IMAGE_1_BOUNDS != vi2.Bounds() {
+		t.Fatalf("Invalid bounds: %v", vi2.Bounds())
+	}
+	vi3, format, err := DecodeBytes(b, nil)
+	checkError(t, err)
+	defer vi3.Free()
+	if format != FORMAT_TIFF {
+		t.Fatalf("Invalid format: %s", format)
+	}
+}
+
+func Test_DecodeTiffBytesVipsEmpty(t *testing.T) {
+	if _, err := DecodeTiffBytes(nil, nil); err != ErrLoad {
+		t.Fatalf("Expected %v, got %v", ErrLoad, err)
+	}
+}
+
+func Test_DecodeHeifBytesVipsEmpty(t *testing.T) {
+	if _, err := DecodeHeifBytes(nil, nil); err != ErrLoad {
+		t.Fatalf("Expected %v, got %v", ErrLoad, err)
+	}
+}
+
+func Test_DecodeSvgBytesVipsEmpty(t *testing.T) {
+	if _, err := DecodeSvgBytes(nil, nil); err != ErrLoad {
+		t.Fatalf("Expected %v, got %v", ErrLoad, err)
+	}
+}
+
+func Test_DecodePdfBytesVipsEmpty(t *testing.T) {
+	if _, err := DecodePdfBytes(nil, nil); err != ErrLoad {
+		t.Fatalf("Expected %v, got %v", ErrLoad, err)
+	}
+}
+
+func Test_DecodeJp2kBytesVipsEmpty(t *testing.T) {
+	if _, err := DecodeJp2kBytes(nil, nil); err != ErrLoad {
+		t.Fatalf("Expected %v, got %v", ErrLoad, err)
+	}
+}
+
+func Test_DecodeJxlBytesVipsEmpty(t *testing.T) {
+	if _, err := DecodeJxlBytes(nil, nil); err != ErrLoad {
+		t.Fatalf("Expected %v, got %v", ErrLoad, err)
+	}
+}
+
+func Test_DecodeJpegFileVips(t *testing.T) {
+	options := DecodeJpegOptions{DecodeOptions: DecodeOptions{Access: VIPS_ACCESS_SEQUENTIAL}}
+	test_DecodeFileVips(t, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, func(file string) (*VipsImage, error) {
+		return DecodeJpegFile(file, &options)
+	}).Free()
+}
+
+func Test_DecodePngFileVips(t *testing.T) {
+	options := DecodeOptions{Access: VIPS_ACCESS_SEQUENTIAL}
+	test_DecodeFileVips(t, "benchmark_images/1.png", BENCHMARK_IMAGE_1_BOUNDS, func(file string) (*VipsImage, error) {
+		return DecodePngFile(file, &options)
+	}).Free()
+}
+
+func Test_DecodeWebpFileVips(t *testing.T) {
+	options := DecodeWebpOptions{DecodeOptions: DecodeOptions{Access: VIPS_ACCESS_SEQUENTIAL}}
+	test_DecodeFileVips(t, "benchmark_images/1.webp", BENCHMARK_IMAGE_1_BOUNDS, func(file string) (*VipsImage, error) {
+		return DecodeWebpFile(file, &options)
+	}).Free()
+}
+
+func Test_DecodeFileVips(t *testing.T) {
+	tests := map[string]string{
+		"benchmark_images/1.jpg":  FORMAT_JPEG,
+		"benchmark_images/1.png":  FORMAT_PNG,
+		"benchmark_images/1.webp": FORMAT_WEBP,
+	}
+	for file, expected := range tests {
+		var format string
+		test_DecodeFileVips(t, file, BENCHMARK_IMAGE_1_BOUNDS, func(file string) (*VipsImage, error) {
+			vi, f, err := DecodeFile(file, nil)
+			format = f
+			return vi, err
+		}).Free()
+		if expected != format {
+			t.Fatalf("Invalid format for %s: %s", file, format)
+		}
+	}
+}
+
+func Test_ProbeVips(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	imageReader, err := os.Open("benchmark_images/1.jpg")
+	checkError(t, err)
+	defer imageReader.Close()
+	h, err := Probe(imageReader)
+	checkError(t, err)
+	expected := Header{
+		Width:          BENCHMARK_IMAGE_1_BOUNDS.Dx(),
+		Height:         BENCHMARK_IMAGE_1_BOUNDS.Dy(),
+		Bands:          3,
+		Interpretation: VIPS_INTERPRETATION_sRGB,
+		Format:         FORMAT_JPEG,
+		Orientation:    1,
+		HasProfile:     true,
+	}
+	if expected != h {
+		t.Fatalf("Invalid header: %+v", h)
+	}
+}
+
+func Test_DetectFormatBytesVips(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	for file, expected := range map[string]string{
+		"benchmark_images/1.jpg":                FORMAT_JPEG,
+		"benchmark_images/32x24x3_animated.gif": FORMAT_GIF,
+	} {
+		b, err := ioutil.ReadFile(file)
+		checkError(t, err)
+		format, err := DetectFormatBytes(b)
+		checkError(t, err)
+		if expected != format {
+			t.Fatalf("Invalid format for %s: %s", file, format)
+		}
+	}
+	if _, err := DetectFormatBytes(nil); err == nil {
+		t.Fatal("Expected an error for an empty buffer")
+	}
+}
+
+func Test_ProbeFileVips(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	h, err := ProbeFile("benchmark_images/1_bw.jpg")
+	checkError(t, err)
+	if h.Interpretation != VIPS_INTERPRETATION_B_W || h.Bands != 1 || h.HasProfile {
+		t.Fatalf("Invalid header: %+v", h)
+	}
+	if h.ColorModel() != color.GrayModel {
+		t.Fatal("Invalid color model")
+	}
+}
+
+func Test_ProbeFile16BitVips(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	h, err := ProbeFile("benchmark_images/8x6_16bit.png")
+	checkError(t, err)
+	if h.Interpretation != VIPS_INTERPRETATION_RGB16 || h.Bands != 3 {
+		t.Fatalf("Invalid header: %+v", h)
+	}
+	if h.ColorModel() != color.NRGBA64Model {
+		t.Fatal("Invalid color model")
+	}
+	vi, _, err := DecodeFile("benchmark_images/8x6_16bit.png", nil)
+	checkError(t, err)
+	defer vi.Free()
+	if vi.ColorModel() != h.ColorModel() {
+		t.Fatal("Invalid image color model")
+	}
+}
+
+func Test_DecodeConfigVips(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	imageReader, err := os.Open("benchmark_images/1.png")
+	checkError(t, err)
+	defer imageReader.Close()
+	c, format, err := DecodeConfig(imageReader)
+	checkError(t, err)
+	if format != FORMAT_PNG {
+		t.Fatalf("Invalid format: %s", format)
+	}
+	if BENCHMARK_IMAGE_1_BOUNDS.Size() != image.Pt(c.Width, c.Height) {
+		t.Fatalf("Invalid dimensions: %dx%d", c.Width, c.Height)
+	}
+}
+
 func Test_DecodeGifMagick(t *testing.T) {
 	options := DecodeMagickOptions{DecodeOptions: DecodeOptions{Access: VIPS_ACCESS_SEQUENTIAL}}
 	test_DecodeMagickVips(t, "benchmark_images/1.gif", BENCHMARK_IMAGE_1_BOUNDS, &options).Free()
@@ -77,6 +390,30 @@ func Test_DecodeWebpMagick(t *testing.T) {
 	test_DecodeMagickVips(t, "benchmark_images/1.webp", BENCHMARK_IMAGE_1_BOUNDS, &options).Free()
 }
 
+func Test_DecodeVips(t *testing.T) {
+	tests := map[string]string{
+		"benchmark_images/1.jpg":  FORMAT_JPEG,
+		"benchmark_images/1.png":  FORMAT_PNG,
+		"benchmark_images/1.webp": FORMAT_WEBP,
+	}
+	for file, expected := range tests {
+		vi, format := test_DecodeAnyVips(t, file, BENCHMARK_IMAGE_1_BOUNDS, nil)
+		vi.Free()
+		if expected != format {
+			t.Fatalf("Invalid format for %s: %s", file, format)
+		}
+	}
+}
+
+func Test_DecodeVipsWithOptions(t *testing.T) {
+	options := DecodeFormatOptions{Jpeg: &DecodeJpegOptions{Shrink: 2}}
+	vi, format := test_DecodeAnyVips(t, "benchmark_images/1.jpg", image.Rect(0, 0, BENCHMARK_IMAGE_1_BOUNDS.Dx()/2, BENCHMARK_IMAGE_1_BOUNDS.Dy()/2), &options)
+	vi.Free()
+	if format != FORMAT_JPEG {
+		t.Fatalf("Invalid format: %s", format)
+	}
+}
+
 func Benchmark_DecodeGifNative(b *testing.B) {
 	benchmark_DecodeNative(b, "benchmark_images/1.gif")
 }
@@ -118,6 +455,27 @@ func Benchmark_DecodeWebpVips(b *testing.B) {
 	})
 }
 
+func Benchmark_DecodeJpegAnyVips(b *testing.B) {
+	benchmark_DecodeVips(b, "benchmark_images/1.jpg", func(imageReader io.Reader) (*VipsImage, error) {
+		vi, _, err := Decode(imageReader, nil)
+		return vi, err
+	})
+}
+
+func Benchmark_DecodeJpegFileVips(b *testing.B) {
+	err := Initialize()
+	defer ThreadShutdown()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		vi, err := DecodeJpegFile("benchmark_images/1.jpg", nil)
+		checkError(b, err)
+		vi.Free()
+	}
+}
+
 func Benchmark_DecodeGifMagick(b *testing.B) {
 	benchmark_DecodeVips(b, "benchmark_images/1.gif", func(imageReader io.Reader) (*VipsImage, error) {
 		return DecodeMagickReader(imageReader, nil)
@@ -159,6 +517,18 @@ func Benchmark_DecodeConfigWebpNative(b *testing.B) {
 	benchmark_DecodeConfigNative(b, "benchmark_images/1.webp", BENCHMARK_IMAGE_1_BOUNDS.Size())
 }
 
+func Benchmark_DecodeConfigJpegVips(b *testing.B) {
+	benchmark_DecodeConfigVips(b, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS.Size())
+}
+
+func Benchmark_DecodeConfigPngVips(b *testing.B) {
+	benchmark_DecodeConfigVips(b, "benchmark_images/1.png", BENCHMARK_IMAGE_1_BOUNDS.Size())
+}
+
+func Benchmark_DecodeConfigWebpVips(b *testing.B) {
+	benchmark_DecodeConfigVips(b, "benchmark_images/1.webp", BENCHMARK_IMAGE_1_BOUNDS.Size())
+}
+
 func benchmark_DecodeVips(b *testing.B, file string, runner func(io.Reader) (*VipsImage, error)) {
 	err := Initialize()
 	defer ThreadShutdown()
@@ -195,6 +565,51 @@ func decodeVips(t testing.TB, file string, bounds image.Rectangle, runner func(i
 	return vi
 }
 
+func test_DecodeAnyVips(t testing.TB, file string, bounds image.Rectangle, options *DecodeFormatOptions) (*VipsImage, string) {
+	var format string
+	vi := test_DecodeVips(t, file, bounds, func(imageReader io.Reader) (*VipsImage, error) {
+		vi, f, err := Decode(imageReader, options)
+		format = f
+		return vi, err
+	})
+	return vi, format
+}
+
+func test_DecodeFileVips(t testing.TB, file string, bounds image.Rectangle, runner func(string) (*VipsImage, error)) *VipsImage {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vi, err := runner(file)
+	if err != nil {
+		t.Fatalf("%s: %s", err, ErrorBuffer())
+	}
+	if bounds != vi.Bounds() {
+		t.Fatalf("Invalid bounds for %s: %v", file, vi.Bounds())
+	}
+	return vi
+}
+
+func checkAnimation(t testing.TB, vi *VipsImage, pageHeight int, delay []int) {
+	animation := vi.Animation()
+	if animation.PageHeight != pageHeight {
+		t.Fatalf("Invalid page height: %d", animation.PageHeight)
+	}
+	if animation.Frames != len(delay) {
+		t.Fatalf("Invalid frame count: %d", animation.Frames)
+	}
+	if len(animation.Delay) != len(delay) {
+		t.Fatalf("Invalid delays: %v", animation.Delay)
+	}
+	for i := range delay {
+		if animation.Delay[i] != delay[i] {
+			t.Fatalf("Invalid delays: %v", animation.Delay)
+		}
+	}
+}
+
 func test_DecodeGifVips(t testing.TB, file string, bounds image.Rectangle, options *DecodeGifOptions) *VipsImage {
 	return test_DecodeVips(t, file, bounds, func(imageReader io.Reader) (*VipsImage, error) {
 		return DecodeGifReader(imageReader, options)
@@ -219,6 +634,18 @@ func test_DecodeWebpVips(t testing.TB, file string, bounds image.Rectangle, opti
 	})
 }
 
+func test_DecodePdfVips(t testing.TB, file string, bounds image.Rectangle, options *DecodePdfOptions) *VipsImage {
+	return test_DecodeVips(t, file, bounds, func(imageReader io.Reader) (*VipsImage, error) {
+		return DecodePdfReader(imageReader, options)
+	})
+}
+
+func test_DecodeSvgVips(t testing.TB, file string, bounds image.Rectangle, options *DecodeSvgOptions) *VipsImage {
+	return test_DecodeVips(t, file, bounds, func(imageReader io.Reader) (*VipsImage, error) {
+		return DecodeSvgReader(imageReader, options)
+	})
+}
+
 func test_DecodeMagickVips(t testing.TB, file string, bounds image.Rectangle, options *DecodeMagickOptions) *VipsImage {
 	return test_DecodeVips(t, file, bounds, func(imageReader io.Reader) (*VipsImage, error) {
 		return DecodeMagickReader(imageReader, options)
@@ -260,6 +687,29 @@ func benchmark_DecodeConfigNative(b *testing.B, file string, dimensions image.Po
 	}
 }
 
+func benchmark_DecodeConfigVips(b *testing.B, file string, dimensions image.Point) {
+	err := Initialize()
+	defer ThreadShutdown()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		imageReader, err := os.Open(file)
+		if err != nil {
+			b.Fatal(err)
+		}
+		c, _, err := DecodeConfig(imageReader)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if dimensions != image.Pt(c.Width, c.Height) {
+			b.Fatalf("Invalid dimensions for %s: %dx%d", file, c.Width, c.Height)
+		}
+		imageReader.Close()
+	}
+}
+
 func checkError(t testing.TB, err error) {
 	if err != nil {
 		t.Error(err)
@@ -2,6 +2,7 @@ package govips
 
 import (
 	"bytes"
+	_ "golang.org/x/image/tiff"
 	_ "golang.org/x/image/webp"
 	"image"
 	"image/gif"
@@ -41,8 +42,114 @@ func Test_EncodeWebpNative(t *testing.T) {
 	t.Skip("Native does not support encoding to WEBP...")
 }
 
-func Test_EncodeGifVips(t *testing.T) {
-	t.Skip("Vips does not support encoding to GIF...")
+func Test_EncodeGifFileVips(t *testing.T) {
+	vi := test_DecodeJpegVips(t, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	defer vi.Free()
+	file, err := ioutil.TempFile("", "")
+	checkError(t, err)
+	defer os.Remove(file.Name())
+	defer file.Close()
+	options := EncodeGifOptions{Effort: 1}
+	err = EncodeGifFile(vi, file, &options)
+	checkError(t, err)
+	file.Seek(0, io.SeekStart)
+	checkEncoded(t, file, "gif", BENCHMARK_IMAGE_1_BOUNDS.Size())
+}
+
+func Test_EncodeGifBytesVips(t *testing.T) {
+	vi := test_DecodeJpegVips(t, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	defer vi.Free()
+	options := EncodeGifOptions{Bitdepth: 4, Dither: FLOAT_ZERO, Loop: INT_ZERO}
+	b, err := EncodeGifBytes(vi, &options)
+	checkError(t, err)
+	checkEncoded(t, bytes.NewReader(b), "gif", BENCHMARK_IMAGE_1_BOUNDS.Size())
+	g, err := gif.DecodeAll(bytes.NewReader(b))
+	checkError(t, err)
+	if len(g.Image[0].Palette) > 16 {
+		t.Fatalf("Invalid palette size: %d", len(g.Image[0].Palette))
+	}
+}
+
+func Test_EncodeHeifBytesVips(t *testing.T) {
+	vi := test_DecodeJpegVips(t, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	defer vi.Free()
+	options := EncodeHeifOptions{Q: 60}
+	b, err := EncodeHeifBytes(vi, &options)
+	checkError(t, err)
+	checkProbed(t, b, FORMAT_HEIF, BENCHMARK_IMAGE_1_BOUNDS.Size())
+}
+
+func Test_EncodeAvifVips(t *testing.T) {
+	vi := test_DecodeJpegVips(t, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	defer vi.Free()
+	var w bytes.Buffer
+	options := EncodeHeifOptions{Q: 60, Compression: VIPS_HEIF_COMPRESSION_AV1, Effort: 2, SubsampleMode: VIPS_SUBSAMPLE_OFF}
+	err := EncodeHeif(vi, &w, &options)
+	checkError(t, err)
+	checkProbed(t, w.Bytes(), FORMAT_AVIF, BENCHMARK_IMAGE_1_BOUNDS.Size())
+}
+
+func Test_EncodeJp2kVips(t *testing.T) {
+	vi := test_DecodeJpegVips(t, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	defer vi.Free()
+	var w bytes.Buffer
+	options := EncodeJp2kOptions{Q: 40, TileWidth: 256, TileHeight: 256, SubsampleMode: VIPS_SUBSAMPLE_OFF}
+	err := EncodeJp2k(vi, &w, &options)
+	checkError(t, err)
+	checkProbed(t, w.Bytes(), FORMAT_JP2K, BENCHMARK_IMAGE_1_BOUNDS.Size())
+}
+
+func Test_EncodeJp2kBytesVips(t *testing.T) {
+	vi := test_DecodeJpegVips(t, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	defer vi.Free()
+	options := EncodeJp2kOptions{Lossless: true}
+	b, err := EncodeJp2kBytes(vi, &options)
+	checkError(t, err)
+	checkProbed(t, b, FORMAT_JP2K, BENCHMARK_IMAGE_1_BOUNDS.Size())
+}
+
+func Test_EncodeJxlVips(t *testing.T) {
+	vi := test_DecodeJpegVips(t, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	defer vi.Free()
+	var w bytes.Buffer
+	options := EncodeJxlOptions{Distance: 1.5, Effort: 3, Tier: 2}
+	err := EncodeJxl(vi, &w, &options)
+	checkError(t, err)
+	checkProbed(t, w.Bytes(), FORMAT_JXL, BENCHMARK_IMAGE_1_BOUNDS.Size())
+}
+
+func Test_EncodeJxlBytesVips(t *testing.T) {
+	vi := test_DecodeJpegVips(t, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	defer vi.Free()
+	options := EncodeJxlOptions{Q: 60, Effort: 1}
+	b, err := EncodeJxlBytes(vi, &options)
+	checkError(t, err)
+	checkProbed(t, b, FORMAT_JXL, BENCHMARK_IMAGE_1_BOUNDS.Size())
+}
+
+func Test_EncodeGifBytesVipsAnimated(t *testing.T) {
+	options := DecodeGifOptions{N: ALL_PAGES}
+	bounds := image.Rect(0, 0, ANIMATED_IMAGE_FRAME_BOUNDS.Dx(), ANIMATED_IMAGE_FRAME_BOUNDS.Dy()*ANIMATED_IMAGE_FRAMES)
+	vi := test_DecodeGifVips(t, "benchmark_images/32x24x3_animated.gif", bounds, &options)
+	defer vi.Free()
+	vi2, err := ExtractAreaFrames(vi, 4, 4, 16, 12)
+	checkError(t, err)
+	defer vi2.Free()
+	b, err := EncodeGifBytes(vi2, nil)
+	checkError(t, err)
+	g, err := gif.DecodeAll(bytes.NewReader(b))
+	checkError(t, err)
+	if len(g.Image) != ANIMATED_IMAGE_FRAMES {
+		t.Fatalf("Invalid frame count: %d", len(g.Image))
+	}
+	if image.Pt(g.Config.Width, g.Config.Height) != image.Pt(16, 12) {
+		t.Fatalf("Invalid dimensions: %dx%d", g.Config.Width, g.Config.Height)
+	}
+	for i, delay := range []int{10, 20, 30} {
+		if g.Delay[i] != delay {
+			t.Fatalf("Invalid delays: %v", g.Delay)
+		}
+	}
 }
 
 func Test_EncodeJpegFileVips(t *testing.T) {
@@ -59,6 +166,39 @@ func Test_EncodeJpegFileVips(t *testing.T) {
 	checkEncoded(t, file, "jpeg", BENCHMARK_IMAGE_1_BOUNDS.Size())
 }
 
+func Test_EncodeJpegVips(t *testing.T) {
+	vi := test_DecodeJpegVips(t, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	defer vi.Free()
+	var w bytes.Buffer
+	options := EncodeJpegOptions{Q: 92}
+	err := EncodeJpeg(vi, &w, &options)
+	checkError(t, err)
+	checkEncoded(t, bytes.NewReader(w.Bytes()), "jpeg", BENCHMARK_IMAGE_1_BOUNDS.Size())
+}
+
+func Test_EncodeJpegPipeVips(t *testing.T) {
+	vi := test_DecodeJpegVips(t, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	defer vi.Free()
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(EncodeJpeg(vi, w, nil))
+	}()
+	checkEncoded(t, r, "jpeg", BENCHMARK_IMAGE_1_BOUNDS.Size())
+	_, err := io.Copy(ioutil.Discard, r)
+	checkError(t, err)
+}
+
+func Test_EncodeJpegWriterErrorVips(t *testing.T) {
+	vi := test_DecodeJpegVips(t, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	defer vi.Free()
+	r, w := io.Pipe()
+	r.Close()
+	if err := EncodeJpeg(vi, w, nil); err != io.ErrClosedPipe {
+		t.Fatalf("Invalid error: %v", err)
+	}
+	ErrorBuffer()
+}
+
 func Test_EncodeJpegBytesVips(t *testing.T) {
 	vi := test_DecodeJpegVips(t, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
 	defer vi.Free()
@@ -82,6 +222,16 @@ func Test_EncodePngFileVips(t *testing.T) {
 	checkEncoded(t, file, "png", BENCHMARK_IMAGE_1_BOUNDS.Size())
 }
 
+func Test_EncodePngVips(t *testing.T) {
+	vi := test_DecodePngVips(t, "benchmark_images/1.png", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	defer vi.Free()
+	var w bytes.Buffer
+	options := EncodePngOptions{Compression: 6}
+	err := EncodePng(vi, &w, &options)
+	checkError(t, err)
+	checkEncoded(t, bytes.NewReader(w.Bytes()), "png", BENCHMARK_IMAGE_1_BOUNDS.Size())
+}
+
 func Test_EncodePngBytesVips(t *testing.T) {
 	vi := test_DecodePngVips(t, "benchmark_images/1.png", BENCHMARK_IMAGE_1_BOUNDS, nil)
 	defer vi.Free()
@@ -91,6 +241,41 @@ func Test_EncodePngBytesVips(t *testing.T) {
 	checkEncoded(t, bytes.NewReader(b), "png", BENCHMARK_IMAGE_1_BOUNDS.Size())
 }
 
+func Test_EncodeTiffFileVips(t *testing.T) {
+	vi := test_DecodeJpegVips(t, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	defer vi.Free()
+	file, err := ioutil.TempFile("", "")
+	checkError(t, err)
+	defer os.Remove(file.Name())
+	defer file.Close()
+	options := EncodeTiffOptions{Compression: VIPS_TIFF_COMPRESSION_LZW}
+	err = EncodeTiffFile(vi, file, &options)
+	checkError(t, err)
+	file.Seek(0, io.SeekStart)
+	checkEncoded(t, file, "tiff", BENCHMARK_IMAGE_1_BOUNDS.Size())
+}
+
+func Test_EncodeTiffPipeVips(t *testing.T) {
+	vi := test_DecodeJpegVips(t, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	defer vi.Free()
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(EncodeTiff(vi, w, &EncodeTiffOptions{Compression: VIPS_TIFF_COMPRESSION_LZW}))
+	}()
+	checkEncoded(t, r, "tiff", BENCHMARK_IMAGE_1_BOUNDS.Size())
+	_, err := io.Copy(ioutil.Discard, r)
+	checkError(t, err)
+}
+
+func Test_EncodeTiffBytesVips(t *testing.T) {
+	vi := test_DecodeJpegVips(t, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	defer vi.Free()
+	options := EncodeTiffOptions{Compression: VIPS_TIFF_COMPRESSION_DEFLATE, Predictor: VIPS_TIFF_PREDICTOR_HORIZONTAL, Tile: true, TileWidth: 256, TileHeight: 256}
+	b, err := EncodeTiffBytes(vi, &options)
+	checkError(t, err)
+	checkEncoded(t, bytes.NewReader(b), "tiff", BENCHMARK_IMAGE_1_BOUNDS.Size())
+}
+
 func Test_EncodeWebpFileVips(t *testing.T) {
 	vi := test_DecodeWebpVips(t, "benchmark_images/1.webp", BENCHMARK_IMAGE_1_BOUNDS, nil)
 	defer vi.Free()
@@ -105,6 +290,16 @@ func Test_EncodeWebpFileVips(t *testing.T) {
 	checkEncoded(t, file, "webp", BENCHMARK_IMAGE_1_BOUNDS.Size())
 }
 
+func Test_EncodeWebpVips(t *testing.T) {
+	vi := test_DecodeWebpVips(t, "benchmark_images/1.webp", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	defer vi.Free()
+	var w bytes.Buffer
+	options := EncodeWebpOptions{Q: 92}
+	err := EncodeWebp(vi, &w, &options)
+	checkError(t, err)
+	checkEncoded(t, bytes.NewReader(w.Bytes()), "webp", BENCHMARK_IMAGE_1_BOUNDS.Size())
+}
+
 func Test_EncodeWebpBytesVips(t *testing.T) {
 	vi := test_DecodeWebpVips(t, "benchmark_images/1.webp", BENCHMARK_IMAGE_1_BOUNDS, nil)
 	defer vi.Free()
@@ -114,6 +309,61 @@ func Test_EncodeWebpBytesVips(t *testing.T) {
 	checkEncoded(t, bytes.NewReader(b), "webp", BENCHMARK_IMAGE_1_BOUNDS.Size())
 }
 
+func Test_EncodeWebpBytesVipsAnimated(t *testing.T) {
+	options := DecodeGifOptions{N: ALL_PAGES}
+	bounds := image.Rect(0, 0, ANIMATED_IMAGE_FRAME_BOUNDS.Dx(), ANIMATED_IMAGE_FRAME_BOUNDS.Dy()*ANIMATED_IMAGE_FRAMES)
+	vi := test_DecodeGifVips(t, "benchmark_images/32x24x3_animated.gif", bounds, &options)
+	defer vi.Free()
+	encodeOptions := EncodeWebpOptions{Lossless: true, KMax: INT_ZERO, MinSize: true, Delay: []int{50, 60, 70}, Loop: 3}
+	b, err := EncodeWebpBytes(vi, &encodeOptions)
+	checkError(t, err)
+	vi2, err := DecodeWebpBytes(b, &DecodeWebpOptions{N: ALL_PAGES})
+	checkError(t, err)
+	defer vi2.Free()
+	if bounds != vi2.Bounds() {
+		t.Fatalf("Invalid bounds: %v", vi2.Bounds())
+	}
+	checkAnimation(t, vi2, ANIMATED_IMAGE_FRAME_BOUNDS.Dy(), []int{50, 60, 70})
+	if loop := vi2.Animation().Loop; loop != 3 {
+		t.Fatalf("Invalid loop: %d", loop)
+	}
+}
+
+func Test_EncodeWebpBytesVipsStrip(t *testing.T) {
+	vi := test_DecodeJpegVips(t, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	defer vi.Free()
+	if !vi.HasProfile() {
+		t.Fatal("Expected the source image to have a profile")
+	}
+	for _, options := range []*EncodeWebpOptions{nil, {}, {KeepMetadata: true}} {
+		b, err := EncodeWebpBytes(vi, options)
+		checkError(t, err)
+		vi2, err := DecodeWebpBytes(b, nil)
+		checkError(t, err)
+		defer vi2.Free()
+		keep := options != nil && options.KeepMetadata
+		if vi2.HasProfile() != keep {
+			t.Fatalf("Invalid profile with %+v", options)
+		}
+	}
+}
+
+func Test_EncodeWebpBytesVipsDelay(t *testing.T) {
+	options := DecodeGifOptions{N: ALL_PAGES}
+	bounds := image.Rect(0, 0, ANIMATED_IMAGE_FRAME_BOUNDS.Dx(), ANIMATED_IMAGE_FRAME_BOUNDS.Dy()*ANIMATED_IMAGE_FRAMES)
+	vi := test_DecodeGifVips(t, "benchmark_images/32x24x3_animated.gif", bounds, &options)
+	defer vi.Free()
+	if _, err := EncodeWebpBytes(vi, &EncodeWebpOptions{Delay: []int{50, 60}}); err == nil {
+		t.Fatal("Expected an error for mismatched delays")
+	}
+	b, err := EncodeWebpBytes(vi, &EncodeWebpOptions{Delay: []int{40}})
+	checkError(t, err)
+	vi2, err := DecodeWebpBytes(b, &DecodeWebpOptions{N: ALL_PAGES})
+	checkError(t, err)
+	defer vi2.Free()
+	checkAnimation(t, vi2, ANIMATED_IMAGE_FRAME_BOUNDS.Dy(), []int{40, 40, 40})
+}
+
 func Benchmark_EncodeGifNative(b *testing.B) {
 	m := decodeNative(b, "benchmark_images/1.gif", BENCHMARK_IMAGE_1_BOUNDS)
 	b.ResetTimer()
@@ -152,11 +402,28 @@ func Benchmark_EncodeWebpNative(b *testing.B) {
 }
 
 func Benchmark_EncodeGifFileVips(b *testing.B) {
-	b.Skip("Vips does not support encoding to GIF...")
+	benchmark_EncodeVips(b, func() *VipsImage {
+		return test_DecodeJpegVips(b, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	}, func(vi *VipsImage) {
+		file, err := ioutil.TempFile("", "")
+		defer os.Remove(file.Name())
+		defer file.Close()
+		checkError(b, err)
+		err = EncodeGifFile(vi, file, nil)
+		checkError(b, err)
+		file.Seek(0, io.SeekStart)
+		checkEncoded(b, file, "gif", BENCHMARK_IMAGE_1_BOUNDS.Size())
+	})
 }
 
 func Benchmark_EncodeGifBytesVips(b *testing.B) {
-	b.Skip("Vips does not support encoding to GIF...")
+	benchmark_EncodeVips(b, func() *VipsImage {
+		return test_DecodeJpegVips(b, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	}, func(vi *VipsImage) {
+		buf, err := EncodeGifBytes(vi, nil)
+		checkError(b, err)
+		checkEncoded(b, bytes.NewReader(buf), "gif", BENCHMARK_IMAGE_1_BOUNDS.Size())
+	})
 }
 
 func Benchmark_EncodeJpegFileVips(b *testing.B) {
@@ -309,6 +576,18 @@ func benchmark_EncodeVips(b *testing.B, decoder func() *VipsImage, runner func(*
 	}
 }
 
+// checkProbed is used for formats that the standard library can not decode.
+func checkProbed(t testing.TB, b []byte, format string, dimensions image.Point) {
+	h, err := ProbeBytes(b)
+	checkError(t, err)
+	if h.Format != format {
+		t.Fatalf("Incorrectly encoded %s to %s", format, h.Format)
+	}
+	if dimensions != image.Pt(h.Width, h.Height) {
+		t.Fatalf("Invalid dimensions for %s: %dx%d", format, h.Width, h.Height)
+	}
+}
+
 func checkEncoded(t testing.TB, r io.Reader, format string, dimensions image.Point) {
 	c, f, err := image.DecodeConfig(r)
 	checkError(t, err)
@@ -1,8 +1,17 @@
 package govips
 
 import (
+	"bytes"
+	"image"
 	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
+	"unsafe"
 )
 
 func TestVipsImage_HasProfile(t *testing.T) {
@@ -94,3 +103,473 @@ func Test_GrayVipsImage(t *testing.T) {
 		t.Fatalf("Invalid color: %v", gray.At(0, 0))
 	}
 }
+
+func Test_NewFromMemory(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	pix := []byte{10, 20, 30, 40, 50, 60}
+	vi, err := NewFromMemory(pix, 3, 2, 1, VIPS_FORMAT_UCHAR)
+	checkError(t, err)
+	defer vi.Free()
+	if image.Rect(0, 0, 3, 2) != vi.Bounds() {
+		t.Fatalf("Invalid bounds: %v", vi.Bounds())
+	}
+	gray, err := NewGrayVipsImage(vi)
+	checkError(t, err)
+	if *gray.At(2, 1).(*color.Gray) != (color.Gray{60}) {
+		t.Fatalf("Invalid color: %v", gray.At(2, 1))
+	}
+	if _, err := NewFromMemory(pix, 4, 2, 1, VIPS_FORMAT_UCHAR); err == nil {
+		t.Fatal("Expected an error for a short buffer")
+	}
+}
+
+func Test_NewFromMemoryPinned(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	streamsLock.Lock()
+	before := len(streams)
+	streamsLock.Unlock()
+	vi, err := NewFromMemory(bytes.Repeat([]byte{42}, 64*64), 64, 64, 1, VIPS_FORMAT_UCHAR)
+	checkError(t, err)
+	runtime.GC()
+	gray, err := NewGrayVipsImage(vi)
+	checkError(t, err)
+	if *gray.At(63, 63).(*color.Gray) != (color.Gray{42}) {
+		t.Fatalf("Invalid color: %v", gray.At(63, 63))
+	}
+	// The region held by gray references the image, so both have to be released before it is finalized.
+	gray.Free()
+	streamsLock.Lock()
+	after := len(streams)
+	streamsLock.Unlock()
+	if after != before {
+		t.Fatalf("Expected the pinned memory to be released, %d entries left", after-before)
+	}
+}
+
+func Test_FromImage(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	bounds := image.Rect(0, 0, 6, 4)
+	nrgba := image.NewNRGBA(bounds)
+	nrgba.SetNRGBA(5, 3, color.NRGBA{200, 100, 50, 255})
+	rgba := image.NewRGBA(bounds)
+	rgba.SetRGBA(5, 3, color.RGBA{100, 50, 25, 128})
+	gray := image.NewGray(bounds)
+	gray.SetGray(5, 3, color.Gray{77})
+	cmyk := image.NewCMYK(bounds)
+	cmyk.SetCMYK(5, 3, color.CMYK{10, 20, 30, 40})
+	ycbcr := image.NewYCbCr(bounds, image.YCbCrSubsampleRatio420)
+	for i := range ycbcr.Y {
+		ycbcr.Y[i] = 120
+	}
+	for i := range ycbcr.Cb {
+		ycbcr.Cb[i] = 90
+		ycbcr.Cr[i] = 160
+	}
+	tests := []struct {
+		m        image.Image
+		expected color.Color
+	}{
+		{nrgba, color.NRGBA{200, 100, 50, 255}},
+		{rgba, color.NRGBA{199, 99, 49, 128}},
+		{gray, color.Gray{77}},
+		{cmyk, color.CMYK{10, 20, 30, 40}},
+		{ycbcr, color.NRGBAModel.Convert(color.YCbCr{120, 90, 160})},
+		{nrgba.SubImage(image.Rect(2, 1, 6, 4)), color.NRGBA{200, 100, 50, 255}},
+	}
+	for _, test := range tests {
+		vi, err := FromImage(test.m)
+		checkError(t, err)
+		defer vi.Free()
+		size := test.m.Bounds().Size()
+		if image.Rect(0, 0, size.X, size.Y) != vi.Bounds() {
+			t.Fatalf("Invalid bounds for %T: %v", test.m, vi.Bounds())
+		}
+		var c color.Color
+		switch test.expected.(type) {
+		case color.Gray:
+			gray, err := NewGrayVipsImage(vi)
+			checkError(t, err)
+			c = *gray.At(size.X-1, size.Y-1).(*color.Gray)
+		case color.CMYK:
+			cmyk, err := NewCMYKVipsImage(vi)
+			checkError(t, err)
+			c = *cmyk.At(size.X-1, size.Y-1).(*color.CMYK)
+		default:
+			nrgba, err := NewNRGBAVipsImage(vi)
+			checkError(t, err)
+			c = *nrgba.At(size.X-1, size.Y-1).(*color.NRGBA)
+		}
+		if !similarColor(c, test.expected, 2) {
+			t.Fatalf("Invalid color for %T: %v != %v", test.m, c, test.expected)
+		}
+	}
+}
+
+func similarColor(a, b color.Color, tolerance uint32) bool {
+	r1, g1, b1, a1 := a.RGBA()
+	r2, g2, b2, a2 := b.RGBA()
+	diff := func(x, y uint32) uint32 {
+		if x > y {
+			return x - y
+		}
+		return y - x
+	}
+	tolerance *= 0x101
+	return diff(r1, r2) <= tolerance && diff(g1, g2) <= tolerance && diff(b1, b2) <= tolerance && diff(a1, a2) <= tolerance
+}
+
+func TestVipsImage_ToNRGBA(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	vi := test_DecodeJpegVips(t, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	defer vi.Free()
+	nrgba, err := vi.ToNRGBA()
+	checkError(t, err)
+	if BENCHMARK_IMAGE_1_BOUNDS != nrgba.Bounds() {
+		t.Fatalf("Invalid bounds: %v", nrgba.Bounds())
+	}
+	if nrgba.NRGBAAt(0, 0) != (color.NRGBA{249, 249, 249, 255}) {
+		t.Fatalf("Invalid color: %v", nrgba.NRGBAAt(0, 0))
+	}
+}
+
+func TestVipsImage_ToGray(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	vi := test_DecodeJpegVips(t, "benchmark_images/1_bw.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	defer vi.Free()
+	gray, err := vi.ToGray()
+	checkError(t, err)
+	if gray.GrayAt(0, 0) != (color.Gray{249}) {
+		t.Fatalf("Invalid color: %v", gray.GrayAt(0, 0))
+	}
+}
+
+func TestVipsImage_ToCMYK(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	vi := test_DecodeJpegVips(t, "benchmark_images/1_cmyk.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	defer vi.Free()
+	cmyk, err := vi.ToCMYK()
+	checkError(t, err)
+	if cmyk.CMYKAt(0, 0) != (color.CMYK{0, 0, 0, 6}) {
+		t.Fatalf("Invalid color: %v", cmyk.CMYKAt(0, 0))
+	}
+}
+
+func TestVipsImage_ToRGBA64(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	m := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	m.SetNRGBA(1, 1, color.NRGBA{255, 0, 0, 255})
+	vi, err := FromImage(m)
+	checkError(t, err)
+	defer vi.Free()
+	rgba64, err := vi.ToRGBA64()
+	checkError(t, err)
+	if rgba64.RGBA64At(1, 1) != (color.RGBA64{0xffff, 0, 0, 0xffff}) {
+		t.Fatalf("Invalid color: %v", rgba64.RGBA64At(1, 1))
+	}
+	if rgba64.RGBA64At(0, 0) != (color.RGBA64{}) {
+		t.Fatalf("Invalid color: %v", rgba64.RGBA64At(0, 0))
+	}
+}
+
+func TestVipsImage_ToImage(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	vi := test_DecodeJpegVips(t, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	defer vi.Free()
+	r := image.Rect(100, 200, 164, 248)
+	m, err := vi.ToImage(r)
+	checkError(t, err)
+	if r != m.Bounds() {
+		t.Fatalf("Invalid bounds: %v", m.Bounds())
+	}
+	nrgba, err := NewNRGBAVipsImage(vi)
+	checkError(t, err)
+	if *nrgba.At(150, 220).(*color.NRGBA) != m.(*image.NRGBA).NRGBAAt(150, 220) {
+		t.Fatalf("Invalid color: %v", m.At(150, 220))
+	}
+}
+
+func Test_NRGBA64VipsImage(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	samples := []uint16{0xffff, 0x8000, 0x0101, 0x4000}
+	vi, err := NewFromMemory(uint16Bytes(samples), 1, 1, 4, VIPS_FORMAT_USHORT)
+	checkError(t, err)
+	nrgba64, err := NewNRGBA64VipsImage(vi)
+	checkError(t, err)
+	defer nrgba64.Free()
+	if nrgba64.ColorModel() != color.NRGBA64Model {
+		t.Fatal("Invalid color model")
+	}
+	if *nrgba64.At(0, 0).(*color.NRGBA64) != (color.NRGBA64{0xffff, 0x8000, 0x0101, 0x4000}) {
+		t.Fatalf("Invalid color: %v", nrgba64.At(0, 0))
+	}
+}
+
+func Test_Gray16VipsImage(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	samples := []uint16{0x0102, 0xfedc}
+	vi, err := NewFromMemory(uint16Bytes(samples), 2, 1, 1, VIPS_FORMAT_USHORT)
+	checkError(t, err)
+	gray16, err := NewGray16VipsImage(vi)
+	checkError(t, err)
+	defer gray16.Free()
+	if gray16.ColorModel() != color.Gray16Model {
+		t.Fatal("Invalid color model")
+	}
+	if *gray16.At(1, 0).(*color.Gray16) != (color.Gray16{0xfedc}) {
+		t.Fatalf("Invalid color: %v", gray16.At(1, 0))
+	}
+}
+
+func Test_SampleVipsImage(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	samples := []float32{0.25, -1.5, 2, 1e6, 0, 0.125}
+	vi, err := NewFromMemory((*[24]byte)(unsafe.Pointer(&samples[0]))[:], 2, 1, 3, VIPS_FORMAT_FLOAT)
+	checkError(t, err)
+	sample, err := NewSampleVipsImage(vi)
+	checkError(t, err)
+	defer sample.Free()
+	if sample.Sample(0, 0, 1) != -1.5 {
+		t.Fatalf("Invalid sample: %v", sample.Sample(0, 0, 1))
+	}
+	for band, expected := range []float64{1e6, 0, 0.125} {
+		if sample.Samples(1, 0)[band] != expected {
+			t.Fatalf("Invalid samples: %v", sample.Samples(1, 0))
+		}
+	}
+}
+
+func uint16Bytes(samples []uint16) []byte {
+	return (*[1 << 20]byte)(unsafe.Pointer(&samples[0]))[: len(samples)*2 : len(samples)*2]
+}
+
+func Test_MutableNRGBAVipsImage(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	m, err := NewMutableNRGBAVipsImage(8, 6)
+	checkError(t, err)
+	defer m.Free()
+	var _ draw.Image = m
+	draw.Draw(m, image.Rect(2, 2, 6, 4), image.NewUniform(color.NRGBA{255, 0, 0, 255}), image.ZP, draw.Src)
+	m.Set(7, 5, color.NRGBA{0, 0, 255, 128})
+	m.Invalidate()
+	b, err := EncodePngBytes(m.VipsImage, nil)
+	checkError(t, err)
+	decoded, err := png.Decode(bytes.NewReader(b))
+	checkError(t, err)
+	tests := map[image.Point]color.NRGBA{
+		image.Pt(0, 0): {0, 0, 0, 0},
+		image.Pt(3, 3): {255, 0, 0, 255},
+		image.Pt(7, 5): {0, 0, 255, 128},
+	}
+	for p, expected := range tests {
+		if c := color.NRGBAModel.Convert(decoded.At(p.X, p.Y)); c != expected {
+			t.Fatalf("Invalid color at %v: %v", p, c)
+		}
+	}
+}
+
+func TestVipsImage_ToMutableNRGBA(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	gray := image.NewGray(image.Rect(0, 0, 4, 4))
+	gray.SetGray(1, 1, color.Gray{100})
+	vi, err := FromImage(gray)
+	checkError(t, err)
+	defer vi.Free()
+	m, err := vi.ToMutableNRGBA()
+	checkError(t, err)
+	defer m.Free()
+	if *m.At(1, 1).(*color.NRGBA) != (color.NRGBA{100, 100, 100, 255}) {
+		t.Fatalf("Invalid color: %v", m.At(1, 1))
+	}
+	m.SetNRGBA(1, 1, color.NRGBA{1, 2, 3, 255})
+	m.Invalidate()
+	nrgba, err := m.ToNRGBA()
+	checkError(t, err)
+	if nrgba.NRGBAAt(1, 1) != (color.NRGBA{1, 2, 3, 255}) {
+		t.Fatalf("Invalid color: %v", nrgba.NRGBAAt(1, 1))
+	}
+}
+
+func TestVipsImage_Fields(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	vi, err := FromImage(image.NewGray(image.Rect(0, 0, 2, 2)))
+	checkError(t, err)
+	defer vi.Free()
+	vi2, err := vi.SetInt("govips-int", 42)
+	checkError(t, err)
+	defer vi2.Free()
+	vi3, err := vi2.SetDouble("govips-double", 1.5)
+	checkError(t, err)
+	defer vi3.Free()
+	vi4, err := vi3.SetString("govips-string", "hello")
+	checkError(t, err)
+	defer vi4.Free()
+	vi5, err := vi4.SetBlob("govips-blob", []byte{1, 2, 3})
+	checkError(t, err)
+	defer vi5.Free()
+	if vi.HasField("govips-int") || vi4.HasField("govips-blob") {
+		t.Fatal("Setting a field modified the original image")
+	}
+	if i, err := vi5.GetInt("govips-int"); err != nil || i != 42 {
+		t.Fatalf("Invalid int: %d, %v", i, err)
+	}
+	if d, err := vi5.GetDouble("govips-double"); err != nil || d != 1.5 {
+		t.Fatalf("Invalid double: %f, %v", d, err)
+	}
+	if s, err := vi5.GetString("govips-string"); err != nil || s != "hello" {
+		t.Fatalf("Invalid string: %q, %v", s, err)
+	}
+	if b, err := vi5.GetBlob("govips-blob"); err != nil || !bytes.Equal(b, []byte{1, 2, 3}) {
+		t.Fatalf("Invalid blob: %v, %v", b, err)
+	}
+	if _, err := vi5.GetInt("govips-string"); err != ErrField {
+		t.Fatalf("Expected %v, got %v", ErrField, err)
+	}
+	fields := map[string]bool{}
+	for _, field := range vi5.Fields() {
+		fields[field] = true
+	}
+	for _, field := range []string{"width", "govips-int", "govips-double", "govips-string", "govips-blob"} {
+		if !fields[field] {
+			t.Fatalf("Missing field %s: %v", field, vi5.Fields())
+		}
+	}
+	vi6, err := vi5.Remove("govips-int")
+	checkError(t, err)
+	defer vi6.Free()
+	if vi6.HasField("govips-int") || !vi5.HasField("govips-int") {
+		t.Fatal("Field was not removed from the copy only")
+	}
+	vi7, removed, err := vi6.RemoveFields("govips-")
+	checkError(t, err)
+	defer vi7.Free()
+	if removed != 3 || vi7.HasField("govips-string") || !vi6.HasField("govips-string") {
+		t.Fatalf("Invalid number of removed fields: %d", removed)
+	}
+	freed, err := vi5.SetString("govips-string", "freed")
+	checkError(t, err)
+	freed.Free()
+	if _, err := freed.GetString("govips-string"); err != ErrField {
+		t.Fatalf("Expected %v for a freed image, got %v", ErrField, err)
+	}
+	if _, err := freed.GetBlob("govips-blob"); err != ErrField {
+		t.Fatalf("Expected %v for a freed image, got %v", ErrField, err)
+	}
+}
+
+func TestVipsImage_Exif(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	vi := test_ExifVips(t)
+	defer vi.Free()
+	exif := vi.Exif()
+	if exif.Make != "Canon" || exif.Model != "EOS (R) 5" || exif.Copyright != "ACME" {
+		t.Fatalf("Invalid camera: %+v", exif)
+	}
+	if !exif.DateTime.Equal(time.Date(2017, 6, 5, 14, 30, 0, 0, time.UTC)) {
+		t.Fatalf("Invalid date: %v", exif.DateTime)
+	}
+	if !exif.HasGPS || math.Abs(exif.Latitude-51.51) > 1e-9 || math.Abs(exif.Longitude+7.0/60+3.0/3600) > 1e-9 {
+		t.Fatalf("Invalid GPS: %+v", exif)
+	}
+	vi2, _, err := vi.RemoveFields(EXIF_GPS_PREFIX)
+	checkError(t, err)
+	defer vi2.Free()
+	exif = vi2.Exif()
+	if exif.HasGPS || exif.Copyright != "ACME" {
+		t.Fatalf("Invalid EXIF after removing GPS: %+v", exif)
+	}
+}
+
+func TestVipsImage_ExifRemoveGPSJpeg(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	vi := test_ExifVips(t)
+	defer vi.Free()
+	vi2, removed, err := vi.RemoveFields(EXIF_GPS_PREFIX)
+	checkError(t, err)
+	defer vi2.Free()
+	if removed == 0 {
+		t.Fatal("No GPS fields were removed")
+	}
+	b, err := EncodeJpegBytes(vi2, nil)
+	checkError(t, err)
+	vi3, err := DecodeJpegBytes(b, nil)
+	checkError(t, err)
+	defer vi3.Free()
+	exif := vi3.Exif()
+	if exif.HasGPS || exif.Copyright != "ACME" {
+		t.Fatalf("Invalid EXIF after encoding without GPS: %+v", exif)
+	}
+	for _, field := range vi3.Fields() {
+		if strings.HasPrefix(field, EXIF_GPS_PREFIX+"GPS") {
+			t.Fatalf("Unexpected GPS field: %s", field)
+		}
+	}
+}
+
+func test_ExifVips(t *testing.T) *VipsImage {
+	vi, err := FromImage(image.NewGray(image.Rect(0, 0, 2, 2)))
+	checkError(t, err)
+	for _, field := range [][2]string{
+		{"exif-ifd0-Make", "Canon (Canon, ASCII, 6 components, 6 bytes)"},
+		{"exif-ifd0-Model", "EOS (R) 5 (EOS (R) 5, ASCII, 10 components, 10 bytes)"},
+		{"exif-ifd0-Copyright", "ACME (ACME (Photographer), ASCII, 5 components, 5 bytes)"},
+		{"exif-ifd2-DateTimeOriginal", "2017:06:05 14:30:00 (2017:06:05 14:30:00, ASCII, 20 components, 20 bytes)"},
+		{"exif-ifd3-GPSLatitude", "51/1 30/1 3600/100 (51, 30, 36.00, Rational, 3 components, 24 bytes)"},
+		{"exif-ifd3-GPSLatitudeRef", "N (N, ASCII, 2 components, 2 bytes)"},
+		{"exif-ifd3-GPSLongitude", "0/1 7/1 3/1 (0, 7, 3, Rational, 3 components, 24 bytes)"},
+		{"exif-ifd3-GPSLongitudeRef", "W (W, ASCII, 2 components, 2 bytes)"},
+	} {
+		vi2, err := vi.SetString(field[0], field[1])
+		checkError(t, err)
+		vi.Free()
+		vi = vi2
+	}
+	return vi
+}
@@ -0,0 +1,119 @@
+// Package imagefmt registers libvips backed decoders with the standard image package.
+//
+// Import it for side effects:
+//
+//	import _ "github.com/RobCherry/govips/imagefmt"
+//
+// image.Decode uses the first registered format that matches, so when the standard decoders (image/jpeg, image/png,
+// etc.) are also linked in, the decoders registered first take precedence.
+//
+// The returned images wrap libvips memory; type assert to interface{ Free() } to release it early.
+package imagefmt
+
+import (
+	"github.com/RobCherry/govips"
+	"image"
+	"image/color"
+	"io"
+	"io/ioutil"
+)
+
+func init() {
+	image.RegisterFormat("jpeg", "\xff\xd8", decoder(decodeJpeg), configDecoder(decodeJpeg))
+	image.RegisterFormat("png", "\x89PNG\r\n\x1a\n", decoder(decodePng), configDecoder(decodePng))
+	image.RegisterFormat("gif", "GIF8?a", decoder(decodeGif), configDecoder(decodeGif))
+	image.RegisterFormat("webp", "RIFF????WEBPVP8", decoder(decodeWebp), configDecoder(decodeWebp))
+}
+
+func decodeGif(b []byte) (*govips.VipsImage, error) {
+	return govips.DecodeGifBytes(b, nil)
+}
+
+func decodeJpeg(b []byte) (*govips.VipsImage, error) {
+	return govips.DecodeJpegBytes(b, nil)
+}
+
+func decodePng(b []byte) (*govips.VipsImage, error) {
+	return govips.DecodePngBytes(b, nil)
+}
+
+func decodeWebp(b []byte) (*govips.VipsImage, error) {
+	return govips.DecodeWebpBytes(b, nil)
+}
+
+func decoder(decode func([]byte) (*govips.VipsImage, error)) func(io.Reader) (image.Image, error) {
+	return func(r io.Reader) (image.Image, error) {
+		vi, err := decodeReader(r, decode)
+		if err != nil {
+			return nil, err
+		}
+		return wrap(vi)
+	}
+}
+
+func configDecoder(decode func([]byte) (*govips.VipsImage, error)) func(io.Reader) (image.Config, error) {
+	return func(r io.Reader) (image.Config, error) {
+		// Loading is lazy, so only the header is read here.
+		vi, err := decodeReader(r, decode)
+		if err != nil {
+			return image.Config{}, err
+		}
+		defer vi.Free()
+		bounds := vi.Bounds()
+		return image.Config{
+			ColorModel: vi.ColorModel(),
+			Width:      bounds.Dx(),
+			Height:     bounds.Dy(),
+		}, nil
+	}
+}
+
+func decodeReader(r io.Reader, decode func([]byte) (*govips.VipsImage, error)) (*govips.VipsImage, error) {
+	if err := govips.Initialize(); err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return decode(b)
+}
+
+// wrap takes ownership of vi and frees it on failure.
+func wrap(vi *govips.VipsImage) (image.Image, error) {
+	var m image.Image
+	var err error
+	switch vi.ColorModel() {
+	case color.CMYKModel:
+		m, err = govips.NewCMYKVipsImage(vi)
+	case color.GrayModel:
+		m, err = govips.NewGrayVipsImage(vi)
+	case color.Gray16Model:
+		m, err = govips.NewGray16VipsImage(vi)
+	case color.NRGBA64Model:
+		if vi.Interpretation() != govips.VIPS_INTERPRETATION_RGB16 {
+			rgb16, err := govips.Colourspace(vi, govips.VIPS_INTERPRETATION_RGB16, nil)
+			vi.Free()
+			if err != nil {
+				return nil, err
+			}
+			vi = rgb16
+		}
+		m, err = govips.NewNRGBA64VipsImage(vi)
+	default:
+		if vi.Interpretation() != govips.VIPS_INTERPRETATION_sRGB {
+			srgb, err := govips.Colourspace(vi, govips.VIPS_INTERPRETATION_sRGB, nil)
+			vi.Free()
+			if err != nil {
+				return nil, err
+			}
+			vi = srgb
+		}
+		m, err = govips.NewNRGBAVipsImage(vi)
+	}
+	if err != nil {
+		vi.Free()
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,94 @@
+package imagefmt
+
+import (
+	"github.com/RobCherry/govips"
+	"image"
+	"image/color"
+	"os"
+	"testing"
+)
+
+var BENCHMARK_IMAGE_1_BOUNDS = image.Rect(0, 0, 4608, 3456)
+
+func Test_Decode(t *testing.T) {
+	defer govips.ThreadShutdown()
+	m, format := decode(t, "../benchmark_images/1.jpg")
+	defer m.(*govips.NRGBAVipsImage).Free()
+	if format != "jpeg" {
+		t.Fatalf("Invalid format: %s", format)
+	}
+	if BENCHMARK_IMAGE_1_BOUNDS != m.Bounds() {
+		t.Fatalf("Invalid bounds: %v", m.Bounds())
+	}
+	if m.ColorModel() != color.NRGBAModel {
+		t.Fatal("Invalid color model")
+	}
+}
+
+func Test_DecodeGray(t *testing.T) {
+	defer govips.ThreadShutdown()
+	m, _ := decode(t, "../benchmark_images/1_bw.jpg")
+	defer m.(*govips.GrayVipsImage).Free()
+	if m.ColorModel() != color.GrayModel {
+		t.Fatal("Invalid color model")
+	}
+}
+
+func Test_Decode16Bit(t *testing.T) {
+	defer govips.ThreadShutdown()
+	m, format := decode(t, "../benchmark_images/8x6_16bit.png")
+	defer m.(*govips.NRGBA64VipsImage).Free()
+	if format != "png" {
+		t.Fatalf("Invalid format: %s", format)
+	}
+	if m.ColorModel() != color.NRGBA64Model {
+		t.Fatal("Invalid color model")
+	}
+	if c := color.NRGBA64Model.Convert(m.At(7, 5)).(color.NRGBA64); c != (color.NRGBA64{7 * 8191, 5 * 13107, 0x1234, 0xffff}) {
+		t.Fatalf("Invalid color: %v", c)
+	}
+	file, err := os.Open("../benchmark_images/8x6_16bit.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	c, _, err := image.DecodeConfig(file)
+	if err != nil {
+		t.Fatalf("%s: %s", err, govips.ErrorBuffer())
+	}
+	if c.ColorModel != color.NRGBA64Model {
+		t.Fatal("Invalid config color model")
+	}
+}
+
+func Test_DecodeConfig(t *testing.T) {
+	defer govips.ThreadShutdown()
+	file, err := os.Open("../benchmark_images/1.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	c, format, err := image.DecodeConfig(file)
+	if err != nil {
+		t.Fatalf("%s: %s", err, govips.ErrorBuffer())
+	}
+	if format != "png" {
+		t.Fatalf("Invalid format: %s", format)
+	}
+	if BENCHMARK_IMAGE_1_BOUNDS.Size() != image.Pt(c.Width, c.Height) {
+		t.Fatalf("Invalid dimensions: %dx%d", c.Width, c.Height)
+	}
+}
+
+func decode(t testing.TB, path string) (image.Image, string) {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	m, format, err := image.Decode(file)
+	if err != nil {
+		t.Fatalf("%s: %s", err, govips.ErrorBuffer())
+	}
+	return m, format
+}
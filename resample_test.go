@@ -2,7 +2,9 @@ package govips
 
 import (
 	"image"
+	"image/color"
 	_ "image/jpeg"
+	"io/ioutil"
 	"testing"
 )
 
@@ -29,6 +31,24 @@ func Test_Affine(t *testing.T) {
 	}
 }
 
+func Test_ResizeAnimated(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	options := DecodeGifOptions{N: ALL_PAGES}
+	bounds := image.Rect(0, 0, ANIMATED_IMAGE_FRAME_BOUNDS.Dx(), ANIMATED_IMAGE_FRAME_BOUNDS.Dy()*ANIMATED_IMAGE_FRAMES)
+	vi := test_DecodeGifVips(t, "benchmark_images/32x24x3_animated.gif", bounds, &options)
+	defer vi.Free()
+	vi2, err := ResizeFrames(vi, 0.5, 0.5, VIPS_KERNEL_LANCZOS3)
+	checkError(t, err)
+	defer vi2.Free()
+	if bounds.Size().Div(2) != vi2.Bounds().Size() {
+		t.Fatalf("Invalid bounds: %v", vi2.Bounds())
+	}
+	checkAnimation(t, vi2, ANIMATED_IMAGE_FRAME_BOUNDS.Dy()/2, []int{100, 200, 300})
+}
+
 func Test_Similarity(t *testing.T) {
 	err := Initialize()
 	defer ThreadShutdown()
@@ -165,3 +185,106 @@ func Test_ShrinkVVips(t *testing.T) {
 		t.Fatalf("Invalid bounds: %v", vi2.Bounds())
 	}
 }
+
+func Test_Thumbnail(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	b, err := ioutil.ReadFile("benchmark_images/1.jpg")
+	checkError(t, err)
+	tests := []struct {
+		width, height int
+		options       ThumbnailOptions
+		expected      image.Point
+	}{
+		{300, 300, ThumbnailOptions{}, image.Pt(300, 225)},
+		{400, 0, ThumbnailOptions{}, image.Pt(400, 300)},
+		{0, 150, ThumbnailOptions{}, image.Pt(200, 150)},
+		{300, 300, ThumbnailOptions{Crop: VIPS_INTERESTING_CENTRE}, image.Pt(300, 300)},
+		{300, 300, ThumbnailOptions{Size: VIPS_SIZE_FORCE}, image.Pt(300, 300)},
+		{10000, 10000, ThumbnailOptions{Size: VIPS_SIZE_DOWN}, BENCHMARK_IMAGE_1_BOUNDS.Size()},
+		{300, 300, ThumbnailOptions{Intent: INT_ZERO}, image.Pt(300, 225)},
+		{300, 300, ThumbnailOptions{Intent: VIPS_INTENT_SATURATION}, image.Pt(300, 225)},
+	}
+	for _, test := range tests {
+		vi, err := Thumbnail(b, test.width, test.height, &test.options)
+		checkError(t, err)
+		defer vi.Free()
+		if test.expected != vi.Bounds().Size() {
+			t.Fatalf("Invalid bounds for thumbnail of %dx%d with %+v: %v", test.width, test.height, test.options, vi.Bounds())
+		}
+	}
+	if _, err := Thumbnail(nil, 300, 300, nil); err != ErrThumbnail {
+		t.Fatalf("Expected %v, got %v", ErrThumbnail, err)
+	}
+}
+
+func Test_ThumbnailImage(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	vi := test_DecodeJpegVips(t, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	defer vi.Free()
+	vi2, err := ThumbnailImage(vi, 300, 300, &ThumbnailOptions{Linear: true})
+	checkError(t, err)
+	defer vi2.Free()
+	if image.Pt(300, 225) != vi2.Bounds().Size() {
+		t.Fatalf("Invalid bounds: %v", vi2.Bounds())
+	}
+}
+
+func Test_ResizeTo(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	vi := test_DecodeJpegVips(t, "benchmark_images/1.jpg", BENCHMARK_IMAGE_1_BOUNDS, nil)
+	defer vi.Free()
+	tests := []struct {
+		width, height int
+		options       ResizeOptions
+		expected      image.Point
+	}{
+		{300, 300, ResizeOptions{Fit: FitInside}, image.Pt(300, 225)},
+		{300, 300, ResizeOptions{Fit: FitContain}, image.Pt(300, 300)},
+		{300, 300, ResizeOptions{Fit: FitCover}, image.Pt(300, 300)},
+		{300, 300, ResizeOptions{Fit: FitFill}, image.Pt(300, 300)},
+		{300, 300, ResizeOptions{Fit: FitOutside}, image.Pt(400, 300)},
+		{400, 0, ResizeOptions{Fit: FitCover}, image.Pt(400, 300)},
+		{10000, 10000, ResizeOptions{WithoutEnlargement: true}, BENCHMARK_IMAGE_1_BOUNDS.Size()},
+		{5000, 100, ResizeOptions{Fit: FitCover, WithoutEnlargement: true}, image.Pt(4608, 100)},
+	}
+	for _, test := range tests {
+		vi2, err := ResizeTo(vi, test.width, test.height, &test.options)
+		checkError(t, err)
+		defer vi2.Free()
+		if test.expected != vi2.Bounds().Size() {
+			t.Fatalf("Invalid bounds for %dx%d with %+v: %v", test.width, test.height, test.options, vi2.Bounds())
+		}
+	}
+}
+
+func Test_ResizeToContain(t *testing.T) {
+	err := Initialize()
+	defer ThreadShutdown()
+	defer checkErrorBuffer(t)
+	checkError(t, err)
+	options := DecodeGifOptions{N: ALL_PAGES}
+	bounds := image.Rect(0, 0, ANIMATED_IMAGE_FRAME_BOUNDS.Dx(), ANIMATED_IMAGE_FRAME_BOUNDS.Dy()*ANIMATED_IMAGE_FRAMES)
+	vi := test_DecodeGifVips(t, "benchmark_images/32x24x3_animated.gif", bounds, &options)
+	defer vi.Free()
+	vi2, err := ResizeTo(vi, 16, 16, &ResizeOptions{Fit: FitContain, Background: []float64{255, 0, 255, 255}})
+	checkError(t, err)
+	defer vi2.Free()
+	if image.Pt(16, 16*ANIMATED_IMAGE_FRAMES) != vi2.Bounds().Size() {
+		t.Fatalf("Invalid bounds: %v", vi2.Bounds())
+	}
+	checkAnimation(t, vi2, 16, []int{100, 200, 300})
+	nrgba, err := vi2.ToNRGBA()
+	checkError(t, err)
+	if nrgba.NRGBAAt(0, 0) != (color.NRGBA{255, 0, 255, 255}) {
+		t.Fatalf("Invalid background: %v", nrgba.NRGBAAt(0, 0))
+	}
+}
@@ -0,0 +1,103 @@
+package govips
+
+/*
+#cgo pkg-config: vips
+#include <vips/vips.h>
+*/
+import "C"
+
+import (
+	"io"
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+// Go values may not be retained by C, so readers and writers are handed to libvips as an id into this registry.
+// An entry is released when libvips finalizes the stream that refers to it. Pinned Go memory wrapped by an image is
+// registered the same way and unpinned when the image is finalized.
+var (
+	streamsLock sync.Mutex
+	streams     = make(map[int]interface{})
+	streamsNext int
+)
+
+func registerStream(s interface{}) int {
+	streamsLock.Lock()
+	defer streamsLock.Unlock()
+	streamsNext++
+	streams[streamsNext] = s
+	return streamsNext
+}
+
+func lookupStream(id C.int) interface{} {
+	streamsLock.Lock()
+	defer streamsLock.Unlock()
+	return streams[int(id)]
+}
+
+//export govipsStreamRelease
+func govipsStreamRelease(id C.int) {
+	streamsLock.Lock()
+	s := streams[int(id)]
+	delete(streams, int(id))
+	streamsLock.Unlock()
+	if p, ok := s.(*runtime.Pinner); ok {
+		p.Unpin()
+	}
+}
+
+type writerTarget struct {
+	w   io.Writer
+	err error
+}
+
+//export govipsTargetWrite
+func govipsTargetWrite(id C.int, data unsafe.Pointer, length C.gint64) C.gint64 {
+	t, ok := lookupStream(id).(*writerTarget)
+	if !ok || t.err != nil {
+		return -1
+	}
+	if length > 1<<30 {
+		length = 1 << 30
+	}
+	n, err := t.w.Write((*[1 << 30]byte)(data)[:length:length])
+	if err != nil {
+		t.err = err
+		return -1
+	}
+	return C.gint64(n)
+}
+
+//export govipsSourceRead
+func govipsSourceRead(id C.int, buffer unsafe.Pointer, length C.gint64) C.gint64 {
+	r, ok := lookupStream(id).(io.Reader)
+	if !ok {
+		return -1
+	}
+	if length > 1<<30 {
+		length = 1 << 30
+	}
+	b := (*[1 << 30]byte)(buffer)[:length:length]
+	n, err := io.ReadAtLeast(r, b, 1)
+	if n > 0 {
+		return C.gint64(n)
+	}
+	if err == io.EOF {
+		return 0
+	}
+	return -1
+}
+
+//export govipsSourceSeek
+func govipsSourceSeek(id C.int, offset C.gint64, whence C.int) C.gint64 {
+	s, ok := lookupStream(id).(io.Seeker)
+	if !ok {
+		return -1
+	}
+	n, err := s.Seek(int64(offset), int(whence))
+	if err != nil {
+		return -1
+	}
+	return C.gint64(n)
+}
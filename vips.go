@@ -11,11 +11,17 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"image/draw"
 	"io"
 	"io/ioutil"
+	"math"
 	"os"
+	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 	"unsafe"
 )
 
@@ -25,6 +31,8 @@ const (
 	FLOAT_ZERO  = -1.0
 	STRING_ZERO = "GOVIPS_STRING_ZERO"
 
+	ALL_PAGES = -1
+
 	DEFAULT_CONCURRENCY      = 0
 	DEFAULT_CACHE_MAX        = 1000
 	DEFAULT_CACHE_MAX_FILES  = 100
@@ -35,18 +43,29 @@ var (
 	ErrInitialize = errors.New("Failed to initialize libvips")
 	ErrConfigure  = errors.New("Failed to configure libvips")
 
-	ErrLoad = errors.New("Failed to load image")
-	ErrSave = errors.New("Failed to save image")
+	ErrLoad   = errors.New("Failed to load image")
+	ErrSave   = errors.New("Failed to save image")
+	ErrFormat = errors.New("Unsupported image format")
+	ErrMemory = errors.New("Failed to create image from memory")
+	ErrExport = errors.New("Failed to export image to memory")
+	ErrField  = errors.New("Missing or mismatched image field")
+	ErrCopy   = errors.New("Failed to copy image")
 
+	ErrFrames       = errors.New("Failed to join image frames")
 	ErrEmbed        = errors.New("Failed to embed image")
 	ErrCrop         = errors.New("Failed to crop image")
 	ErrShrink       = errors.New("Failed to shrink image")
 	ErrReduce       = errors.New("Failed to reduce image")
 	ErrResize       = errors.New("Failed to resize image")
+	ErrThumbnail    = errors.New("Failed to thumbnail image")
 	ErrAffine       = errors.New("Failed to affine image")
 	ErrBlur         = errors.New("Failed to blur image")
 	ErrSharpen      = errors.New("Failed to sharpen image")
 	ErrFlatten      = errors.New("Failed to flatten image")
+	ErrAutorotate   = errors.New("Failed to autorotate image")
+	ErrRotate       = errors.New("Failed to rotate image")
+	ErrFlip         = errors.New("Failed to flip image")
+	ErrCast         = errors.New("Failed to cast image")
 	ErrColourspace  = errors.New("Failed to convert colourspace of image")
 	ErrICCTransform = errors.New("Failed to transform colourspace of image")
 )
@@ -145,10 +164,50 @@ func ErrorBuffer() error {
 // Constants
 
 var (
-	cApplicationNane    = C.CString("govips")
-	cVIPS_META_ICC_NAME = C.CString(C.VIPS_META_ICC_NAME)
+	cApplicationNane       = C.CString("govips")
+	cVIPS_META_ICC_NAME    = C.CString(C.VIPS_META_ICC_NAME)
+	cVIPS_META_ORIENTATION = C.CString(C.VIPS_META_ORIENTATION)
+	cHEIF_COMPRESSION      = C.CString("heif-compression")
+	cDELAY                 = C.CString("delay")
+	cLOOP                  = C.CString("loop")
+	cN_PAGES               = C.CString("n-pages")
+)
+
+const (
+	FORMAT_AVIF   = "avif"
+	FORMAT_GIF    = "gif"
+	FORMAT_HEIF   = "heif"
+	FORMAT_JP2K   = "jp2k"
+	FORMAT_JPEG   = "jpeg"
+	FORMAT_JXL    = "jxl"
+	FORMAT_MAGICK = "magick"
+	FORMAT_PDF    = "pdf"
+	FORMAT_PNG    = "png"
+	FORMAT_SVG    = "svg"
+	FORMAT_TIFF   = "tiff"
+	FORMAT_WEBP   = "webp"
 )
 
+// Loader class names are matched by prefix so that the buffer, source and file variants all resolve to the same format.
+var loaderFormats = []struct {
+	prefix string
+	format string
+}{
+	{"VipsForeignLoadGif", FORMAT_GIF},
+	{"VipsForeignLoadNsgif", FORMAT_GIF},
+	{"VipsForeignLoadHeif", FORMAT_HEIF},
+	{"VipsForeignLoadJp2k", FORMAT_JP2K},
+	{"VipsForeignLoadJpeg", FORMAT_JPEG},
+	{"VipsForeignLoadJxl", FORMAT_JXL},
+	{"VipsForeignLoadMagick", FORMAT_MAGICK},
+	{"VipsForeignLoadPdf", FORMAT_PDF},
+	{"VipsForeignLoadPng", FORMAT_PNG},
+	{"VipsForeignLoadSpng", FORMAT_PNG},
+	{"VipsForeignLoadSvg", FORMAT_SVG},
+	{"VipsForeignLoadTiff", FORMAT_TIFF},
+	{"VipsForeignLoadWebp", FORMAT_WEBP},
+}
+
 type VipsInterpretation int
 
 func (i VipsInterpretation) toC() C.VipsInterpretation {
@@ -273,6 +332,109 @@ const (
 	VIPS_WEBP_PRESET_LAST
 )
 
+type HeifCompression int
+
+func (c HeifCompression) toC() C.VipsForeignHeifCompression {
+	switch c {
+	case VIPS_HEIF_COMPRESSION_HEVC:
+		return C.VIPS_FOREIGN_HEIF_COMPRESSION_HEVC
+	case VIPS_HEIF_COMPRESSION_AVC:
+		return C.VIPS_FOREIGN_HEIF_COMPRESSION_AVC
+	case VIPS_HEIF_COMPRESSION_JPEG:
+		return C.VIPS_FOREIGN_HEIF_COMPRESSION_JPEG
+	case VIPS_HEIF_COMPRESSION_AV1:
+		return C.VIPS_FOREIGN_HEIF_COMPRESSION_AV1
+	default:
+		return C.VIPS_FOREIGN_HEIF_COMPRESSION_HEVC
+	}
+}
+
+const (
+	VIPS_HEIF_COMPRESSION_HEVC HeifCompression = iota
+	VIPS_HEIF_COMPRESSION_AVC
+	VIPS_HEIF_COMPRESSION_JPEG
+	VIPS_HEIF_COMPRESSION_AV1
+)
+
+type SubsampleMode int
+
+func (m SubsampleMode) toC() C.VipsForeignSubsample {
+	switch m {
+	case VIPS_SUBSAMPLE_AUTO:
+		return C.VIPS_FOREIGN_SUBSAMPLE_AUTO
+	case VIPS_SUBSAMPLE_ON:
+		return C.VIPS_FOREIGN_SUBSAMPLE_ON
+	case VIPS_SUBSAMPLE_OFF:
+		return C.VIPS_FOREIGN_SUBSAMPLE_OFF
+	default:
+		return C.VIPS_FOREIGN_SUBSAMPLE_AUTO
+	}
+}
+
+const (
+	VIPS_SUBSAMPLE_AUTO SubsampleMode = iota
+	VIPS_SUBSAMPLE_ON
+	VIPS_SUBSAMPLE_OFF
+)
+
+type TiffCompression int
+
+func (c TiffCompression) toC() C.VipsForeignTiffCompression {
+	switch c {
+	case VIPS_TIFF_COMPRESSION_NONE:
+		return C.VIPS_FOREIGN_TIFF_COMPRESSION_NONE
+	case VIPS_TIFF_COMPRESSION_JPEG:
+		return C.VIPS_FOREIGN_TIFF_COMPRESSION_JPEG
+	case VIPS_TIFF_COMPRESSION_DEFLATE:
+		return C.VIPS_FOREIGN_TIFF_COMPRESSION_DEFLATE
+	case VIPS_TIFF_COMPRESSION_PACKBITS:
+		return C.VIPS_FOREIGN_TIFF_COMPRESSION_PACKBITS
+	case VIPS_TIFF_COMPRESSION_CCITTFAX4:
+		return C.VIPS_FOREIGN_TIFF_COMPRESSION_CCITTFAX4
+	case VIPS_TIFF_COMPRESSION_LZW:
+		return C.VIPS_FOREIGN_TIFF_COMPRESSION_LZW
+	case VIPS_TIFF_COMPRESSION_WEBP:
+		return C.VIPS_FOREIGN_TIFF_COMPRESSION_WEBP
+	case VIPS_TIFF_COMPRESSION_ZSTD:
+		return C.VIPS_FOREIGN_TIFF_COMPRESSION_ZSTD
+	default:
+		return C.VIPS_FOREIGN_TIFF_COMPRESSION_NONE
+	}
+}
+
+const (
+	VIPS_TIFF_COMPRESSION_NONE TiffCompression = iota
+	VIPS_TIFF_COMPRESSION_JPEG
+	VIPS_TIFF_COMPRESSION_DEFLATE
+	VIPS_TIFF_COMPRESSION_PACKBITS
+	VIPS_TIFF_COMPRESSION_CCITTFAX4
+	VIPS_TIFF_COMPRESSION_LZW
+	VIPS_TIFF_COMPRESSION_WEBP
+	VIPS_TIFF_COMPRESSION_ZSTD
+)
+
+type TiffPredictor int
+
+func (p TiffPredictor) toC() C.VipsForeignTiffPredictor {
+	switch p {
+	case VIPS_TIFF_PREDICTOR_NONE:
+		return C.VIPS_FOREIGN_TIFF_PREDICTOR_NONE
+	case VIPS_TIFF_PREDICTOR_HORIZONTAL:
+		return C.VIPS_FOREIGN_TIFF_PREDICTOR_HORIZONTAL
+	case VIPS_TIFF_PREDICTOR_FLOAT:
+		return C.VIPS_FOREIGN_TIFF_PREDICTOR_FLOAT
+	default:
+		return C.VIPS_FOREIGN_TIFF_PREDICTOR_HORIZONTAL
+	}
+}
+
+const (
+	VIPS_TIFF_PREDICTOR_DEFAULT TiffPredictor = iota
+	VIPS_TIFF_PREDICTOR_NONE
+	VIPS_TIFF_PREDICTOR_HORIZONTAL
+	VIPS_TIFF_PREDICTOR_FLOAT
+)
+
 type VipsExtend int
 
 func (e VipsExtend) toC() C.VipsExtend {
@@ -303,6 +465,48 @@ const (
 	VIPS_EXTEND_BACKGROUND
 )
 
+type VipsAngle int
+
+func (a VipsAngle) toC() C.VipsAngle {
+	switch a {
+	case VIPS_ANGLE_D0:
+		return C.VIPS_ANGLE_D0
+	case VIPS_ANGLE_D90:
+		return C.VIPS_ANGLE_D90
+	case VIPS_ANGLE_D180:
+		return C.VIPS_ANGLE_D180
+	case VIPS_ANGLE_D270:
+		return C.VIPS_ANGLE_D270
+	default:
+		return C.VIPS_ANGLE_D0
+	}
+}
+
+const (
+	VIPS_ANGLE_D0 VipsAngle = iota
+	VIPS_ANGLE_D90
+	VIPS_ANGLE_D180
+	VIPS_ANGLE_D270
+)
+
+type VipsDirection int
+
+func (d VipsDirection) toC() C.VipsDirection {
+	switch d {
+	case VIPS_DIRECTION_HORIZONTAL:
+		return C.VIPS_DIRECTION_HORIZONTAL
+	case VIPS_DIRECTION_VERTICAL:
+		return C.VIPS_DIRECTION_VERTICAL
+	default:
+		return C.VIPS_DIRECTION_HORIZONTAL
+	}
+}
+
+const (
+	VIPS_DIRECTION_HORIZONTAL VipsDirection = iota
+	VIPS_DIRECTION_VERTICAL
+)
+
 type VipsKernel int
 
 func (k VipsKernel) toC() C.VipsKernel {
@@ -333,6 +537,111 @@ const (
 	VIPS_KERNEL_LAST
 )
 
+type VipsSize int
+
+func (s VipsSize) toC() C.VipsSize {
+	switch s {
+	case VIPS_SIZE_BOTH:
+		return C.VIPS_SIZE_BOTH
+	case VIPS_SIZE_UP:
+		return C.VIPS_SIZE_UP
+	case VIPS_SIZE_DOWN:
+		return C.VIPS_SIZE_DOWN
+	case VIPS_SIZE_FORCE:
+		return C.VIPS_SIZE_FORCE
+	default:
+		return C.VIPS_SIZE_BOTH
+	}
+}
+
+const (
+	VIPS_SIZE_BOTH VipsSize = iota
+	VIPS_SIZE_UP
+	VIPS_SIZE_DOWN
+	VIPS_SIZE_FORCE
+)
+
+type VipsInteresting int
+
+func (i VipsInteresting) toC() C.VipsInteresting {
+	switch i {
+	case VIPS_INTERESTING_NONE:
+		return C.VIPS_INTERESTING_NONE
+	case VIPS_INTERESTING_CENTRE:
+		return C.VIPS_INTERESTING_CENTRE
+	case VIPS_INTERESTING_ENTROPY:
+		return C.VIPS_INTERESTING_ENTROPY
+	case VIPS_INTERESTING_ATTENTION:
+		return C.VIPS_INTERESTING_ATTENTION
+	case VIPS_INTERESTING_LOW:
+		return C.VIPS_INTERESTING_LOW
+	case VIPS_INTERESTING_HIGH:
+		return C.VIPS_INTERESTING_HIGH
+	case VIPS_INTERESTING_ALL:
+		return C.VIPS_INTERESTING_ALL
+	default:
+		return C.VIPS_INTERESTING_NONE
+	}
+}
+
+const (
+	VIPS_INTERESTING_NONE VipsInteresting = iota
+	VIPS_INTERESTING_CENTRE
+	VIPS_INTERESTING_ENTROPY
+	VIPS_INTERESTING_ATTENTION
+	VIPS_INTERESTING_LOW
+	VIPS_INTERESTING_HIGH
+	VIPS_INTERESTING_ALL
+)
+
+// ResizeFit is how ResizeTo fits an image into the requested width and height.
+type ResizeFit int
+
+const (
+	// FitInside preserves the aspect ratio and fits within both dimensions.
+	FitInside ResizeFit = iota
+	// FitContain is FitInside letterboxed with the background to exactly the requested size.
+	FitContain
+	// FitCover preserves the aspect ratio and covers both dimensions, cropping the overflow by gravity.
+	FitCover
+	// FitFill ignores the aspect ratio and stretches to exactly the requested size.
+	FitFill
+	// FitOutside preserves the aspect ratio and covers both dimensions without cropping.
+	FitOutside
+)
+
+// Gravity is the edge or corner of the image that CropGravity keeps.
+type Gravity int
+
+const (
+	GravityCentre Gravity = iota
+	GravityNorth
+	GravityNorthEast
+	GravityEast
+	GravitySouthEast
+	GravitySouth
+	GravitySouthWest
+	GravityWest
+	GravityNorthWest
+)
+
+func (g Gravity) rect(size image.Point, width, height int) image.Rectangle {
+	left, top := (size.X-width)/2, (size.Y-height)/2
+	switch g {
+	case GravityNorthWest, GravityWest, GravitySouthWest:
+		left = 0
+	case GravityNorthEast, GravityEast, GravitySouthEast:
+		left = size.X - width
+	}
+	switch g {
+	case GravityNorthWest, GravityNorth, GravityNorthEast:
+		top = 0
+	case GravitySouthWest, GravitySouth, GravitySouthEast:
+		top = size.Y - height
+	}
+	return image.Rect(left, top, left+width, top+height)
+}
+
 type VipsPrecision int
 
 func (p VipsPrecision) toC() C.VipsPrecision {
@@ -384,6 +693,26 @@ const (
 	VIPS_INTENT_LAST
 )
 
+type VipsBandFormat int
+
+func (f VipsBandFormat) toC() C.VipsBandFormat {
+	return C.VipsBandFormat(f)
+}
+
+const (
+	VIPS_FORMAT_NOTSET    VipsBandFormat = C.VIPS_FORMAT_NOTSET
+	VIPS_FORMAT_UCHAR     VipsBandFormat = C.VIPS_FORMAT_UCHAR
+	VIPS_FORMAT_CHAR      VipsBandFormat = C.VIPS_FORMAT_CHAR
+	VIPS_FORMAT_USHORT    VipsBandFormat = C.VIPS_FORMAT_USHORT
+	VIPS_FORMAT_SHORT     VipsBandFormat = C.VIPS_FORMAT_SHORT
+	VIPS_FORMAT_UINT      VipsBandFormat = C.VIPS_FORMAT_UINT
+	VIPS_FORMAT_INT       VipsBandFormat = C.VIPS_FORMAT_INT
+	VIPS_FORMAT_FLOAT     VipsBandFormat = C.VIPS_FORMAT_FLOAT
+	VIPS_FORMAT_COMPLEX   VipsBandFormat = C.VIPS_FORMAT_COMPLEX
+	VIPS_FORMAT_DOUBLE    VipsBandFormat = C.VIPS_FORMAT_DOUBLE
+	VIPS_FORMAT_DPCOMPLEX VipsBandFormat = C.VIPS_FORMAT_DPCOMPLEX
+)
+
 // Image
 
 type VipsImage struct {
@@ -412,6 +741,46 @@ func (v *VipsImage) Bands() int {
 	return int(v.cVipsImage.Bands)
 }
 
+// ColorModel is the color.Model that best represents the pixels, 16-bit interpretations map to the 16-bit models.
+func (v *VipsImage) ColorModel() color.Model {
+	return colorModel(v.Interpretation(), v.Bands())
+}
+
+func colorModel(interpretation VipsInterpretation, bands int) color.Model {
+	switch interpretation {
+	case VIPS_INTERPRETATION_CMYK:
+		return color.CMYKModel
+	case VIPS_INTERPRETATION_B_W:
+		if bands == 1 {
+			return color.GrayModel
+		}
+	case VIPS_INTERPRETATION_GREY16:
+		if bands == 1 {
+			return color.Gray16Model
+		}
+		return color.NRGBA64Model
+	case VIPS_INTERPRETATION_RGB16:
+		return color.NRGBA64Model
+	}
+	return color.NRGBAModel
+}
+
+// Orientation is the EXIF orientation, from 1 (upright) to 8, defaulting to 1 when the image has no orientation.
+func (v *VipsImage) Orientation() int {
+	if v.cVipsImage == nil {
+		return 1
+	}
+	return int(C.govips_get_int(v.cVipsImage, cVIPS_META_ORIENTATION, 1))
+}
+
+// Format is the band format of the pixels, or VIPS_FORMAT_NOTSET for a freed image.
+func (v *VipsImage) Format() VipsBandFormat {
+	if v.cVipsImage == nil {
+		return VIPS_FORMAT_NOTSET
+	}
+	return VipsBandFormat(C.vips_image_get_format(v.cVipsImage))
+}
+
 func (v *VipsImage) HasProfile() bool {
 	if v.cVipsImage == nil {
 		return false
@@ -423,266 +792,2087 @@ func (v *VipsImage) RemoveProfile() {
 	C.vips_image_remove(v.cVipsImage, cVIPS_META_ICC_NAME)
 }
 
-func (v *VipsImage) Free() {
-	if v.cVipsImage != nil {
-		C.g_object_unref(C.gpointer(v.cVipsImage))
-		v.cVipsImage = nil
+// Fields lists the names of all metadata attached to the image, including the parsed exif-, xmp- and iptc- fields.
+func (v *VipsImage) Fields() []string {
+	if v.cVipsImage == nil {
+		return nil
 	}
-	if v.goBytes != nil {
-		v.goBytes = nil
+	cFields := C.vips_image_get_fields(v.cVipsImage)
+	defer C.g_strfreev(cFields)
+	var fields []string
+	for _, cField := range (*[1 << 20]*C.gchar)(unsafe.Pointer(cFields)) {
+		if cField == nil {
+			break
+		}
+		fields = append(fields, C.GoString((*C.char)(cField)))
 	}
+	return fields
 }
 
-func newVipsImage(i *C.struct__VipsImage, b []byte) *VipsImage {
-	return &VipsImage{cVipsImage: i, goBytes: b}
-}
-
-// Decode
-
-type DecodeOptions struct {
-	Access VipsAccess
-	Disc   bool
-}
-
-func (o DecodeOptions) toC() cDecodeOptions {
-	return cDecodeOptions{
-		Access: o.Access.toC(),
-		Disc:   toGBool(o.Disc),
+// HasField reports whether the image has a field called name.
+func (v *VipsImage) HasField(name string) bool {
+	if v.cVipsImage == nil {
+		return false
 	}
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+	return C.vips_image_get_typeof(v.cVipsImage, cName) != 0
 }
 
-type cDecodeOptions struct {
-	Access C.VipsAccess
-	Disc   C.gboolean
+// GetInt returns an int field, or ErrField when the image has no such field or it is not an int.
+func (v *VipsImage) GetInt(name string) (int, error) {
+	if !v.HasField(name) {
+		return 0, ErrField
+	}
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+	var out C.int
+	if C.vips_image_get_int(v.cVipsImage, cName, &out) != 0 {
+		return 0, ErrField
+	}
+	return int(out), nil
 }
 
-func (c *cDecodeOptions) Free() {
+// GetDouble returns a double field, or ErrField when the image has no such field or it is not a double.
+func (v *VipsImage) GetDouble(name string) (float64, error) {
+	if !v.HasField(name) {
+		return 0, ErrField
+	}
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+	var out C.double
+	if C.vips_image_get_double(v.cVipsImage, cName, &out) != 0 {
+		return 0, ErrField
+	}
+	return float64(out), nil
 }
 
-type DecodeGifOptions struct {
-	DecodeOptions
-	Page int
+// GetString returns a string field, or ErrField when the image has no such field or it is not a string. Parsed
+// exif- fields are strings of the form "value (description, format, ...)".
+func (v *VipsImage) GetString(name string) (string, error) {
+	if v.cVipsImage == nil {
+		return "", ErrField
+	}
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+	out := C.govips_get_string(v.cVipsImage, cName)
+	if out == nil {
+		return "", ErrField
+	}
+	return C.GoString(out), nil
 }
 
-func (o DecodeGifOptions) toC() cDecodeGifOptions {
-	return cDecodeGifOptions{
-		cDecodeOptions: o.DecodeOptions.toC(),
-		Page:           C.gint(o.Page),
+// GetBlob returns a copy of the blob, such as the exif-data, xmp-data or iptc-data fields.
+func (v *VipsImage) GetBlob(name string) ([]byte, error) {
+	if v.cVipsImage == nil {
+		return nil, ErrField
+	}
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+	var length C.size_t
+	out := C.govips_get_blob(v.cVipsImage, cName, &length)
+	if out == nil {
+		return nil, ErrField
 	}
+	return C.GoBytes(out, C.int(length)), nil
 }
 
-type cDecodeGifOptions struct {
-	cDecodeOptions
-	Page C.gint
+// SetInt returns a copy of the image with the field set, the image itself is left unchanged as it may be shared by
+// other images in the pipeline.
+func (v *VipsImage) SetInt(name string, value int) (*VipsImage, error) {
+	out, err := copyImage(v)
+	if err != nil {
+		return nil, err
+	}
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+	C.vips_image_set_int(out.cVipsImage, cName, C.int(value))
+	return out, nil
 }
 
-func (c *cDecodeGifOptions) Free() {
-	c.cDecodeOptions.Free()
+func (v *VipsImage) SetDouble(name string, value float64) (*VipsImage, error) {
+	out, err := copyImage(v)
+	if err != nil {
+		return nil, err
+	}
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+	C.vips_image_set_double(out.cVipsImage, cName, C.double(value))
+	return out, nil
 }
 
-type DecodeJpegOptions struct {
-	DecodeOptions
-	Shrink     int
-	Fail       bool
-	Autorotate bool
+func (v *VipsImage) SetString(name string, value string) (*VipsImage, error) {
+	out, err := copyImage(v)
+	if err != nil {
+		return nil, err
+	}
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+	cValue := C.CString(value)
+	defer C.free(unsafe.Pointer(cValue))
+	C.vips_image_set_string(out.cVipsImage, cName, cValue)
+	return out, nil
 }
 
-func (o DecodeJpegOptions) toC() cDecodeJpegOptions {
-	if o.Shrink >= 8 {
-		o.Shrink = 8
-	} else if o.Shrink >= 4 {
-		o.Shrink = 4
-	} else if o.Shrink >= 2 {
-		o.Shrink = 2
-	} else {
-		o.Shrink = 1
+func (v *VipsImage) SetBlob(name string, value []byte) (*VipsImage, error) {
+	out, err := copyImage(v)
+	if err != nil {
+		return nil, err
 	}
-	return cDecodeJpegOptions{
-		cDecodeOptions: o.DecodeOptions.toC(),
-		Shrink:         C.gint(o.Shrink),
-		Fail:           toGBool(o.Fail),
-		Autorotate:     toGBool(o.Autorotate),
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+	var data unsafe.Pointer
+	if len(value) > 0 {
+		data = unsafe.Pointer(&value[0])
 	}
+	C.vips_image_set_blob_copy(out.cVipsImage, cName, data, C.size_t(len(value)))
+	return out, nil
 }
 
-type cDecodeJpegOptions struct {
-	cDecodeOptions
-	Shrink     C.gint
-	Fail       C.gboolean
-	Autorotate C.gboolean
+// Remove returns a copy of the image without the field.
+func (v *VipsImage) Remove(name string) (*VipsImage, error) {
+	out, err := copyImage(v)
+	if err != nil {
+		return nil, err
+	}
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+	C.vips_image_remove(out.cVipsImage, cName)
+	return out, nil
 }
 
-func (c *cDecodeJpegOptions) Free() {
-	c.cDecodeOptions.Free()
+// RemoveFields returns a copy of the image without the fields whose name starts with prefix, along with how many were
+// removed. The EXIF block is rebuilt from the remaining exif- fields when the image is saved, so removing
+// EXIF_GPS_PREFIX strips location data while keeping tags such as the copyright.
+func (v *VipsImage) RemoveFields(prefix string) (*VipsImage, int, error) {
+	out, err := copyImage(v)
+	if err != nil {
+		return nil, 0, err
+	}
+	removed := 0
+	for _, field := range out.Fields() {
+		if !strings.HasPrefix(field, prefix) {
+			continue
+		}
+		cName := C.CString(field)
+		if fromGBool(C.vips_image_remove(out.cVipsImage, cName)) {
+			removed++
+		}
+		C.free(unsafe.Pointer(cName))
+	}
+	return out, removed, nil
 }
 
-type DecodeMagickOptions struct {
-	DecodeOptions
-	AllFrames bool
-	Density   string
-	Page      int
-}
+const (
+	EXIF_IFD0_PREFIX = "exif-ifd0-"
+	EXIF_EXIF_PREFIX = "exif-ifd2-"
+	EXIF_GPS_PREFIX  = "exif-ifd3-"
+)
 
-func (o DecodeMagickOptions) toC() cDecodeMagickOptions {
-	var density *C.char
-	if o.Density == STRING_ZERO {
-		density = C.CString("")
-	} else if o.Density != "" {
-		density = C.CString(o.Density)
+type Exif struct {
+	Make      string
+	Model     string
+	Copyright string
+	DateTime  time.Time
+	HasGPS    bool
+	Latitude  float64
+	Longitude float64
+}
+
+// Exif parses the commonly used tags out of the exif- fields. Missing or malformed tags are left as zero values.
+func (v *VipsImage) Exif() Exif {
+	exif := Exif{
+		Make:      v.exifString(EXIF_IFD0_PREFIX + "Make"),
+		Model:     v.exifString(EXIF_IFD0_PREFIX + "Model"),
+		Copyright: v.exifString(EXIF_IFD0_PREFIX + "Copyright"),
+	}
+	for _, name := range []string{EXIF_EXIF_PREFIX + "DateTimeOriginal", EXIF_IFD0_PREFIX + "DateTime"} {
+		if dateTime, err := time.Parse("2006:01:02 15:04:05", v.exifString(name)); err == nil {
+			exif.DateTime = dateTime
+			break
+		}
 	}
-	return cDecodeMagickOptions{
-		cDecodeOptions: o.DecodeOptions.toC(),
-		AllFrames:      toGBool(o.AllFrames),
-		Density:        density,
-		Page:           C.gint(o.Page),
+	latitude, latitudeErr := parseExifCoordinate(v.exifString(EXIF_GPS_PREFIX+"GPSLatitude"), v.exifString(EXIF_GPS_PREFIX+"GPSLatitudeRef"))
+	longitude, longitudeErr := parseExifCoordinate(v.exifString(EXIF_GPS_PREFIX+"GPSLongitude"), v.exifString(EXIF_GPS_PREFIX+"GPSLongitudeRef"))
+	if latitudeErr == nil && longitudeErr == nil {
+		exif.HasGPS = true
+		exif.Latitude = latitude
+		exif.Longitude = longitude
 	}
+	return exif
 }
 
-type cDecodeMagickOptions struct {
-	cDecodeOptions
-	AllFrames C.gboolean
-	Density   *C.char
-	Page      C.gint
+// exifString returns the raw value of an exif- field, which libvips formats as "value (description, format, ...)".
+func (v *VipsImage) exifString(name string) string {
+	s, err := v.GetString(name)
+	if err != nil {
+		return ""
+	}
+	// ASCII values may contain " (" themselves, but are repeated verbatim as the description.
+	for i := 0; ; i += 2 {
+		j := strings.Index(s[i:], " (")
+		if j < 0 {
+			break
+		}
+		i += j
+		if strings.HasPrefix(s[i+2:], s[:i]+",") {
+			return s[:i]
+		}
+	}
+	if i := strings.Index(s, " ("); i >= 0 {
+		return s[:i]
+	}
+	return s
 }
 
-func (c *cDecodeMagickOptions) Free() {
-	c.cDecodeOptions.Free()
-	if c.Density != nil {
-		C.free(unsafe.Pointer(c.Density))
-		c.Density = nil
+// parseExifCoordinate converts degrees, minutes and seconds rationals such as "51/1 30/1 2629/100" to signed degrees.
+func parseExifCoordinate(value, ref string) (float64, error) {
+	parts := strings.Fields(value)
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("Invalid coordinate: %q", value)
+	}
+	var coordinate float64
+	for i, part := range parts {
+		fraction := strings.SplitN(part, "/", 2)
+		numerator, err := strconv.ParseFloat(fraction[0], 64)
+		if err != nil {
+			return 0, err
+		}
+		denominator := 1.0
+		if len(fraction) == 2 {
+			if denominator, err = strconv.ParseFloat(fraction[1], 64); err != nil {
+				return 0, err
+			}
+		}
+		if denominator == 0 {
+			return 0, fmt.Errorf("Invalid coordinate: %q", value)
+		}
+		coordinate += numerator / denominator / []float64{1, 60, 3600}[i]
 	}
+	if ref == "S" || ref == "W" {
+		coordinate = -coordinate
+	}
+	return coordinate, nil
 }
 
-type DecodeWebpOptions struct {
-	DecodeOptions
-	Shrink int
+type Animation struct {
+	PageHeight int
+	Frames     int
+	Delay      []int
+	Loop       int
 }
 
-func (o DecodeWebpOptions) toC() cDecodeWebpOptions {
-	if o.Shrink < 1 {
-		o.Shrink = 1
+// Animation describes how a multi-page image is stacked vertically into frames of PageHeight rows.
+func (v *VipsImage) Animation() Animation {
+	if v.cVipsImage == nil {
+		return Animation{}
+	}
+	pageHeight := int(C.vips_image_get_page_height(v.cVipsImage))
+	var frames int
+	if pageHeight > 0 {
+		frames = v.Bounds().Dy() / pageHeight
+	}
+	var n C.int
+	var delay []int
+	cDelay := C.govips_get_array_int(v.cVipsImage, cDELAY, &n)
+	if cDelay != nil {
+		delay = make([]int, int(n))
+		for i, d := range (*[1 << 28]C.int)(unsafe.Pointer(cDelay))[:n:n] {
+			delay[i] = int(d)
+		}
 	}
-	return cDecodeWebpOptions{
-		cDecodeOptions: o.DecodeOptions.toC(),
-		Shrink:         C.gint(o.Shrink),
+	return Animation{
+		PageHeight: pageHeight,
+		Frames:     frames,
+		Delay:      delay,
+		Loop:       int(C.govips_get_int(v.cVipsImage, cLOOP, 0)),
 	}
 }
 
-type cDecodeWebpOptions struct {
-	cDecodeOptions
-	Shrink C.gint
+// Pages is the number of pages in the source file, which may be more than were loaded.
+func (v *VipsImage) Pages() int {
+	if v.cVipsImage == nil {
+		return 0
+	}
+	return int(C.govips_get_int(v.cVipsImage, cN_PAGES, 1))
 }
 
-func (c *cDecodeWebpOptions) Free() {
+func (v *VipsImage) Free() {
+	if v.cVipsImage != nil {
+		C.g_object_unref(C.gpointer(v.cVipsImage))
+		v.cVipsImage = nil
+	}
+	if v.goBytes != nil {
+		v.goBytes = nil
+	}
+}
+
+func newVipsImage(i *C.struct__VipsImage, b []byte) *VipsImage {
+	return &VipsImage{cVipsImage: i, goBytes: b}
+}
+
+// Memory
+
+// NewFromMemory wraps pix without copying it, so pix must not be modified while the image or any image derived from it
+// is in use.
+func NewFromMemory(pix []byte, width, height, bands int, format VipsBandFormat) (*VipsImage, error) {
+	interpretation := VIPS_INTERPRETATION_MULTIBAND
+	switch {
+	case bands <= 2 && format == VIPS_FORMAT_USHORT:
+		interpretation = VIPS_INTERPRETATION_GREY16
+	case bands <= 2:
+		interpretation = VIPS_INTERPRETATION_B_W
+	case bands <= 4 && format == VIPS_FORMAT_USHORT:
+		interpretation = VIPS_INTERPRETATION_RGB16
+	case bands <= 4:
+		interpretation = VIPS_INTERPRETATION_sRGB
+	}
+	return newFromMemory(pix, width, height, bands, format, interpretation)
+}
+
+func newFromMemory(pix []byte, width, height, bands int, format VipsBandFormat, interpretation VipsInterpretation) (*VipsImage, error) {
+	if width <= 0 || height <= 0 || bands <= 0 {
+		return nil, fmt.Errorf("Invalid dimensions: %dx%dx%d", width, height, bands)
+	}
+	size := width * height * bands * int(C.vips_format_sizeof(format.toC()))
+	if len(pix) < size {
+		return nil, fmt.Errorf("Invalid buffer size: %d < %d", len(pix), size)
+	}
+	// libvips keeps reading pix after this call returns, so it stays pinned until the image is finalized.
+	pinner := new(runtime.Pinner)
+	pinner.Pin(&pix[0])
+	i := C.govips_image_new_from_memory(unsafe.Pointer(&pix[0]), C.size_t(size), C.int(width), C.int(height), C.int(bands), format.toC(), interpretation.toC(), C.int(registerStream(pinner)))
+	if i == nil {
+		return nil, ErrMemory
+	}
+	return newVipsImage(i, nil), nil
+}
+
+// newFromStride wraps rows of stride bytes, cropping away any padding at the end of each row. The rows are packed
+// into a copy when the last row is truncated, as it is for sub images that end before the right edge.
+func newFromStride(pix []byte, stride, width, height, bands int, interpretation VipsInterpretation) (*VipsImage, error) {
+	rowSize := width * bands
+	if stride == rowSize {
+		return newFromMemory(pix, width, height, bands, VIPS_FORMAT_UCHAR, interpretation)
+	}
+	if stride%bands == 0 && len(pix) >= stride*height {
+		v, err := newFromMemory(pix, stride/bands, height, bands, VIPS_FORMAT_UCHAR, interpretation)
+		if err != nil {
+			return nil, err
+		}
+		defer v.Free()
+		return ExtractArea(v, 0, 0, width, height)
+	}
+	packed := make([]byte, rowSize*height)
+	for y := 0; y < height; y++ {
+		copy(packed[y*rowSize:(y+1)*rowSize], pix[y*stride:])
+	}
+	return newFromMemory(packed, width, height, bands, VIPS_FORMAT_UCHAR, interpretation)
+}
+
+// FromImage wraps the pixels of NRGBA, RGBA, Gray, CMYK and YCbCr images without copying them, any other image is
+// converted to NRGBA first.
+func FromImage(m image.Image) (*VipsImage, error) {
+	bounds := m.Bounds()
+	if bounds.Empty() {
+		return nil, fmt.Errorf("Invalid bounds: %v", bounds)
+	}
+	switch m := m.(type) {
+	case *image.NRGBA:
+		return newFromStride(m.Pix, m.Stride, bounds.Dx(), bounds.Dy(), 4, VIPS_INTERPRETATION_sRGB)
+	case *image.RGBA:
+		v, err := newFromStride(m.Pix, m.Stride, bounds.Dx(), bounds.Dy(), 4, VIPS_INTERPRETATION_sRGB)
+		if err != nil {
+			return nil, err
+		}
+		defer v.Free()
+		var i *C.struct__VipsImage
+		if C.govips_unpremultiply(v.cVipsImage, &i) != 0 {
+			return nil, ErrMemory
+		}
+		return newVipsImage(i, v.goBytes), nil
+	case *image.Gray:
+		return newFromStride(m.Pix, m.Stride, bounds.Dx(), bounds.Dy(), 1, VIPS_INTERPRETATION_B_W)
+	case *image.CMYK:
+		return newFromStride(m.Pix, m.Stride, bounds.Dx(), bounds.Dy(), 4, VIPS_INTERPRETATION_CMYK)
+	case *image.YCbCr:
+		if v, err := fromYCbCr(m); v != nil || err != nil {
+			return v, err
+		}
+	}
+	nrgba := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(nrgba, nrgba.Bounds(), m, bounds.Min, draw.Src)
+	return newFromStride(nrgba.Pix, nrgba.Stride, bounds.Dx(), bounds.Dy(), 4, VIPS_INTERPRETATION_sRGB)
+}
+
+// fromYCbCr returns a nil image when the chroma planes are not aligned with the luma plane, which happens for sub
+// images that start on an odd pixel.
+func fromYCbCr(m *image.YCbCr) (*VipsImage, error) {
+	var xfac, yfac int
+	switch m.SubsampleRatio {
+	case image.YCbCrSubsampleRatio444:
+		xfac, yfac = 1, 1
+	case image.YCbCrSubsampleRatio422:
+		xfac, yfac = 2, 1
+	case image.YCbCrSubsampleRatio420:
+		xfac, yfac = 2, 2
+	case image.YCbCrSubsampleRatio440:
+		xfac, yfac = 1, 2
+	case image.YCbCrSubsampleRatio411:
+		xfac, yfac = 4, 1
+	case image.YCbCrSubsampleRatio410:
+		xfac, yfac = 4, 2
+	default:
+		return nil, nil
+	}
+	bounds := m.Bounds()
+	if bounds.Min.X%xfac != 0 || bounds.Min.Y%yfac != 0 {
+		return nil, nil
+	}
+	cw := (bounds.Dx() + xfac - 1) / xfac
+	ch := (bounds.Dy() + yfac - 1) / yfac
+	y, err := newFromStride(m.Y, m.YStride, bounds.Dx(), bounds.Dy(), 1, VIPS_INTERPRETATION_B_W)
+	if err != nil {
+		return nil, err
+	}
+	defer y.Free()
+	cb, err := newFromStride(m.Cb, m.CStride, cw, ch, 1, VIPS_INTERPRETATION_B_W)
+	if err != nil {
+		return nil, err
+	}
+	defer cb.Free()
+	cr, err := newFromStride(m.Cr, m.CStride, cw, ch, 1, VIPS_INTERPRETATION_B_W)
+	if err != nil {
+		return nil, err
+	}
+	defer cr.Free()
+	var i *C.struct__VipsImage
+	if C.govips_ycbcr_to_srgb(y.cVipsImage, cb.cVipsImage, cr.cVipsImage, &i, C.int(xfac), C.int(yfac)) != 0 {
+		return nil, ErrMemory
+	}
+	return newVipsImage(i, nil), nil
+}
+
+// ToNRGBA copies the whole image into Go memory, converting it to 8-bit sRGB with an alpha channel.
+func (v *VipsImage) ToNRGBA() (*image.NRGBA, error) {
+	return v.toNRGBA(v.Bounds())
+}
+
+// ToGray copies the whole image into Go memory, converting it to 8-bit greyscale and dropping any alpha channel.
+func (v *VipsImage) ToGray() (*image.Gray, error) {
+	return v.toGray(v.Bounds())
+}
+
+// ToCMYK copies the whole image into Go memory, converting it to 8-bit CMYK.
+func (v *VipsImage) ToCMYK() (*image.CMYK, error) {
+	return v.toCMYK(v.Bounds())
+}
+
+// ToRGBA64 copies the whole image into Go memory, converting it to 16-bit premultiplied sRGB.
+func (v *VipsImage) ToRGBA64() (*image.RGBA64, error) {
+	return v.toRGBA64(v.Bounds())
+}
+
+// ToImage copies only the pixels within r into a Go image whose bounds are r clipped to the image. The image type
+// follows the interpretation: Gray for greyscale, CMYK for CMYK, RGBA64 for 16-bit and NRGBA for everything else.
+func (v *VipsImage) ToImage(r image.Rectangle) (image.Image, error) {
+	r = r.Intersect(v.Bounds())
+	if r.Empty() {
+		return nil, fmt.Errorf("Invalid bounds: %v", r)
+	}
+	switch v.Interpretation() {
+	case VIPS_INTERPRETATION_B_W:
+		if v.Bands() == 1 {
+			return v.toGray(r)
+		}
+	case VIPS_INTERPRETATION_GREY16:
+		if v.Bands() == 1 {
+			return v.toRGBA64(r)
+		}
+	case VIPS_INTERPRETATION_CMYK:
+		return v.toCMYK(r)
+	case VIPS_INTERPRETATION_RGB16:
+		return v.toRGBA64(r)
+	}
+	return v.toNRGBA(r)
+}
+
+func (v *VipsImage) toNRGBA(r image.Rectangle) (*image.NRGBA, error) {
+	pix, err := v.toMemory(r, VIPS_INTERPRETATION_sRGB, VIPS_FORMAT_UCHAR, 4, false)
+	if err != nil {
+		return nil, err
+	}
+	return &image.NRGBA{Pix: pix, Stride: r.Dx() * 4, Rect: r}, nil
+}
+
+func (v *VipsImage) toGray(r image.Rectangle) (*image.Gray, error) {
+	pix, err := v.toMemory(r, VIPS_INTERPRETATION_B_W, VIPS_FORMAT_UCHAR, 1, false)
+	if err != nil {
+		return nil, err
+	}
+	return &image.Gray{Pix: pix, Stride: r.Dx(), Rect: r}, nil
+}
+
+func (v *VipsImage) toCMYK(r image.Rectangle) (*image.CMYK, error) {
+	pix, err := v.toMemory(r, VIPS_INTERPRETATION_CMYK, VIPS_FORMAT_UCHAR, 4, false)
+	if err != nil {
+		return nil, err
+	}
+	return &image.CMYK{Pix: pix, Stride: r.Dx() * 4, Rect: r}, nil
+}
+
+func (v *VipsImage) toRGBA64(r image.Rectangle) (*image.RGBA64, error) {
+	pix, err := v.toMemory(r, VIPS_INTERPRETATION_RGB16, VIPS_FORMAT_USHORT, 4, true)
+	if err != nil {
+		return nil, err
+	}
+	// libvips writes samples in host byte order while image.RGBA64 is big endian.
+	if nativeLittleEndian {
+		for i := 0; i < len(pix); i += 2 {
+			pix[i], pix[i+1] = pix[i+1], pix[i]
+		}
+	}
+	return &image.RGBA64{Pix: pix, Stride: r.Dx() * 8, Rect: r}, nil
+}
+
+func (v *VipsImage) toMemory(r image.Rectangle, space VipsInterpretation, format VipsBandFormat, bands int, premultiply bool) ([]byte, error) {
+	if v.cVipsImage == nil {
+		return nil, ErrExport
+	}
+	bounds := v.Bounds()
+	if !r.In(bounds) || r.Empty() {
+		return nil, fmt.Errorf("Invalid bounds: %v", r)
+	}
+	// libvips writes the pixels straight into pix, so there is no intermediate C buffer.
+	pix := make([]byte, r.Dx()*r.Dy()*bands*int(C.vips_format_sizeof(format.toC())))
+	if C.govips_to_memory(v.cVipsImage, C.int(r.Min.X-bounds.Min.X), C.int(r.Min.Y-bounds.Min.Y), C.int(r.Dx()), C.int(r.Dy()), space.toC(), format.toC(), C.int(bands), toGBool(premultiply), unsafe.Pointer(&pix[0]), C.size_t(len(pix))) != 0 {
+		return nil, ErrExport
+	}
+	return pix, nil
+}
+
+// Decode
+
+type DecodeOptions struct {
+	Access VipsAccess
+	Disc   bool
+	// Autorotate applies the EXIF orientation, the JPEG and TIFF loaders do it themselves while the other formats are
+	// rotated after loading.
+	Autorotate bool
+}
+
+func (o DecodeOptions) toC() cDecodeOptions {
+	return cDecodeOptions{
+		Access: o.Access.toC(),
+		Disc:   toGBool(o.Disc),
+	}
+}
+
+type cDecodeOptions struct {
+	Access C.VipsAccess
+	Disc   C.gboolean
+}
+
+func (c *cDecodeOptions) Free() {
+}
+
+type DecodeGifOptions struct {
+	DecodeOptions
+	Page int
+	N    int
+}
+
+func (o DecodeGifOptions) toC() cDecodeGifOptions {
+	if o.N == 0 {
+		o.N = 1
+	}
+	return cDecodeGifOptions{
+		cDecodeOptions: o.DecodeOptions.toC(),
+		Page:           C.gint(o.Page),
+		N:              C.gint(o.N),
+	}
+}
+
+type cDecodeGifOptions struct {
+	cDecodeOptions
+	Page C.gint
+	N    C.gint
+}
+
+func (c *cDecodeGifOptions) Free() {
+	c.cDecodeOptions.Free()
+}
+
+type DecodeHeifOptions struct {
+	DecodeOptions
+	Page      int
+	N         int
+	Thumbnail bool
+}
+
+func (o DecodeHeifOptions) toC() cDecodeHeifOptions {
+	if o.N == 0 {
+		o.N = 1
+	}
+	return cDecodeHeifOptions{
+		cDecodeOptions: o.DecodeOptions.toC(),
+		Page:           C.gint(o.Page),
+		N:              C.gint(o.N),
+		Thumbnail:      toGBool(o.Thumbnail),
+	}
+}
+
+type cDecodeHeifOptions struct {
+	cDecodeOptions
+	Page      C.gint
+	N         C.gint
+	Thumbnail C.gboolean
+}
+
+func (c *cDecodeHeifOptions) Free() {
+	c.cDecodeOptions.Free()
+}
+
+type DecodeJp2kOptions struct {
+	DecodeOptions
+	Page int
+}
+
+func (o DecodeJp2kOptions) toC() cDecodeJp2kOptions {
+	return cDecodeJp2kOptions{
+		cDecodeOptions: o.DecodeOptions.toC(),
+		Page:           C.gint(o.Page),
+	}
+}
+
+type cDecodeJp2kOptions struct {
+	cDecodeOptions
+	Page C.gint
+}
+
+func (c *cDecodeJp2kOptions) Free() {
+	c.cDecodeOptions.Free()
+}
+
+type DecodeJpegOptions struct {
+	DecodeOptions
+	Shrink int
+	Fail   bool
+	// Deprecated: Use DecodeOptions.Autorotate, setting either one makes the loader autorotate.
+	Autorotate bool
+}
+
+func (o DecodeJpegOptions) toC() cDecodeJpegOptions {
+	if o.Shrink >= 8 {
+		o.Shrink = 8
+	} else if o.Shrink >= 4 {
+		o.Shrink = 4
+	} else if o.Shrink >= 2 {
+		o.Shrink = 2
+	} else {
+		o.Shrink = 1
+	}
+	return cDecodeJpegOptions{
+		cDecodeOptions: o.DecodeOptions.toC(),
+		Shrink:         C.gint(o.Shrink),
+		Fail:           toGBool(o.Fail),
+		Autorotate:     toGBool(o.Autorotate || o.DecodeOptions.Autorotate),
+	}
+}
+
+type cDecodeJpegOptions struct {
+	cDecodeOptions
+	Shrink     C.gint
+	Fail       C.gboolean
+	Autorotate C.gboolean
+}
+
+func (c *cDecodeJpegOptions) Free() {
+	c.cDecodeOptions.Free()
+}
+
+type DecodeMagickOptions struct {
+	DecodeOptions
+	AllFrames bool
+	Density   string
+	Page      int
+	N         int
+}
+
+func (o DecodeMagickOptions) toC() cDecodeMagickOptions {
+	var density *C.char
+	if o.Density == STRING_ZERO {
+		density = C.CString("")
+	} else if o.Density != "" {
+		density = C.CString(o.Density)
+	}
+	if o.N == 0 {
+		o.N = 1
+	}
+	return cDecodeMagickOptions{
+		cDecodeOptions: o.DecodeOptions.toC(),
+		AllFrames:      toGBool(o.AllFrames),
+		Density:        density,
+		Page:           C.gint(o.Page),
+		N:              C.gint(o.N),
+	}
+}
+
+type cDecodeMagickOptions struct {
+	cDecodeOptions
+	AllFrames C.gboolean
+	Density   *C.char
+	Page      C.gint
+	N         C.gint
+}
+
+func (c *cDecodeMagickOptions) Free() {
 	c.cDecodeOptions.Free()
+	if c.Density != nil {
+		C.free(unsafe.Pointer(c.Density))
+		c.Density = nil
+	}
+}
+
+type DecodePdfOptions struct {
+	DecodeOptions
+	Page       int
+	N          int
+	Dpi        float64
+	Scale      float64
+	Background []float64
+	Password   string
+}
+
+func (o DecodePdfOptions) toC() cDecodePdfOptions {
+	if o.N == 0 {
+		o.N = 1
+	}
+	if o.Dpi == 0 {
+		o.Dpi = 72
+	}
+	if o.Scale == 0 {
+		o.Scale = 1
+	}
+	if len(o.Background) == 0 {
+		o.Background = []float64{255}
+	}
+	var password *C.char
+	if o.Password != "" {
+		password = C.CString(o.Password)
+	}
+	return cDecodePdfOptions{
+		cDecodeOptions: o.DecodeOptions.toC(),
+		Page:           C.gint(o.Page),
+		N:              C.gint(o.N),
+		Dpi:            C.gdouble(o.Dpi),
+		Scale:          C.gdouble(o.Scale),
+		Background:     newVipsArrayDouble(o.Background),
+		Password:       password,
+	}
+}
+
+type cDecodePdfOptions struct {
+	cDecodeOptions
+	Page       C.gint
+	N          C.gint
+	Dpi        C.gdouble
+	Scale      C.gdouble
+	Background *C.struct__VipsArrayDouble
+	Password   *C.char
+}
+
+func (c *cDecodePdfOptions) Free() {
+	c.cDecodeOptions.Free()
+	if c.Background != nil {
+		vipsArrayDoubleUnref(c.Background)
+		c.Background = nil
+	}
+	if c.Password != nil {
+		C.free(unsafe.Pointer(c.Password))
+		c.Password = nil
+	}
+}
+
+type DecodeSvgOptions struct {
+	DecodeOptions
+	Dpi       float64
+	Scale     float64
+	Unlimited bool
+}
+
+func (o DecodeSvgOptions) toC() cDecodeSvgOptions {
+	if o.Dpi == 0 {
+		o.Dpi = 72
+	}
+	if o.Scale == 0 {
+		o.Scale = 1
+	}
+	return cDecodeSvgOptions{
+		cDecodeOptions: o.DecodeOptions.toC(),
+		Dpi:            C.gdouble(o.Dpi),
+		Scale:          C.gdouble(o.Scale),
+		Unlimited:      toGBool(o.Unlimited),
+	}
+}
+
+type cDecodeSvgOptions struct {
+	cDecodeOptions
+	Dpi       C.gdouble
+	Scale     C.gdouble
+	Unlimited C.gboolean
+}
+
+func (c *cDecodeSvgOptions) Free() {
+	c.cDecodeOptions.Free()
+}
+
+type DecodeTiffOptions struct {
+	DecodeOptions
+	Page int
+	N    int
+}
+
+func (o DecodeTiffOptions) toC() cDecodeTiffOptions {
+	if o.N == 0 {
+		o.N = 1
+	}
+	return cDecodeTiffOptions{
+		cDecodeOptions: o.DecodeOptions.toC(),
+		Page:           C.gint(o.Page),
+		N:              C.gint(o.N),
+		Autorotate:     toGBool(o.Autorotate),
+	}
+}
+
+type cDecodeTiffOptions struct {
+	cDecodeOptions
+	Page       C.gint
+	N          C.gint
+	Autorotate C.gboolean
+}
+
+func (c *cDecodeTiffOptions) Free() {
+	c.cDecodeOptions.Free()
+}
+
+type DecodeWebpOptions struct {
+	DecodeOptions
+	Shrink int
+	Page   int
+	N      int
+}
+
+func (o DecodeWebpOptions) toC() cDecodeWebpOptions {
+	if o.Shrink < 1 {
+		o.Shrink = 1
+	}
+	if o.N == 0 {
+		o.N = 1
+	}
+	return cDecodeWebpOptions{
+		cDecodeOptions: o.DecodeOptions.toC(),
+		Shrink:         C.gint(o.Shrink),
+		Page:           C.gint(o.Page),
+		N:              C.gint(o.N),
+	}
+}
+
+type cDecodeWebpOptions struct {
+	cDecodeOptions
+	Shrink C.gint
+	Page   C.gint
+	N      C.gint
+}
+
+func (c *cDecodeWebpOptions) Free() {
+	c.cDecodeOptions.Free()
+}
+
+// decoded wraps a freshly loaded image and applies the options that are not handled by the libvips loaders.
+func decoded(i *C.struct__VipsImage, b []byte, options DecodeOptions) (*VipsImage, error) {
+	v := newVipsImage(i, b)
+	if !options.Autorotate {
+		return v, nil
+	}
+	defer v.Free()
+	return Autorotate(v)
+}
+
+// DecodeGifReader reads all of r into memory before decoding it.
+//
+// Deprecated: Use DecodeGifStream, which reads r on demand instead of buffering it.
+func DecodeGifReader(r io.Reader, options *DecodeGifOptions) (*VipsImage, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeGifBytes(b, options)
+}
+
+// DecodeGifStream reads r on demand, see DecodeStream.
+func DecodeGifStream(r io.Reader, options *DecodeGifOptions) (*VipsImage, error) {
+	source := newVipsSource(r)
+	defer C.g_object_unref(C.gpointer(source))
+	return decodeGifSource(source, options)
+}
+
+func decodeGifSource(source *C.VipsSource, options *DecodeGifOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &DecodeGifOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	var i *C.struct__VipsImage
+	if C.govips_gifload_source(source, &i, cOptions.Page, cOptions.N, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return decoded(i, nil, options.DecodeOptions)
+}
+
+func DecodeGifBytes(b []byte, options *DecodeGifOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &DecodeGifOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	var i *C.struct__VipsImage
+	if C.govips_gifload_buffer(unsafe.Pointer(&b[0]), C.size_t(len(b)), &i, cOptions.Page, cOptions.N, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return decoded(i, b, options.DecodeOptions)
+}
+
+func DecodeGifFile(path string, options *DecodeGifOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &DecodeGifOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	cFileName := C.CString(path)
+	defer C.free(unsafe.Pointer(cFileName))
+	var i *C.struct__VipsImage
+	if C.govips_gifload(cFileName, &i, cOptions.Page, cOptions.N, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return decoded(i, nil, options.DecodeOptions)
+}
+
+// DecodeHeifReader reads all of r into memory before decoding it.
+//
+// Deprecated: Use DecodeHeifStream, which reads r on demand instead of buffering it.
+func DecodeHeifReader(r io.Reader, options *DecodeHeifOptions) (*VipsImage, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeHeifBytes(b, options)
+}
+
+// DecodeHeifStream reads r on demand, see DecodeStream.
+func DecodeHeifStream(r io.Reader, options *DecodeHeifOptions) (*VipsImage, error) {
+	source := newVipsSource(r)
+	defer C.g_object_unref(C.gpointer(source))
+	return decodeHeifSource(source, options)
+}
+
+func decodeHeifSource(source *C.VipsSource, options *DecodeHeifOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &DecodeHeifOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	var i *C.struct__VipsImage
+	if C.govips_heifload_source(source, &i, cOptions.Page, cOptions.N, cOptions.Thumbnail, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return decoded(i, nil, options.DecodeOptions)
+}
+
+func DecodeHeifBytes(b []byte, options *DecodeHeifOptions) (*VipsImage, error) {
+	if len(b) == 0 {
+		return nil, ErrLoad
+	}
+	if options == nil {
+		options = &DecodeHeifOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	var i *C.struct__VipsImage
+	if C.govips_heifload_buffer(unsafe.Pointer(&b[0]), C.size_t(len(b)), &i, cOptions.Page, cOptions.N, cOptions.Thumbnail, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return decoded(i, b, options.DecodeOptions)
+}
+
+func DecodeHeifFile(path string, options *DecodeHeifOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &DecodeHeifOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	cFileName := C.CString(path)
+	defer C.free(unsafe.Pointer(cFileName))
+	var i *C.struct__VipsImage
+	if C.govips_heifload(cFileName, &i, cOptions.Page, cOptions.N, cOptions.Thumbnail, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return decoded(i, nil, options.DecodeOptions)
+}
+
+// DecodeJp2kReader reads all of r into memory before decoding it.
+//
+// Deprecated: Use DecodeJp2kStream, which reads r on demand instead of buffering it.
+func DecodeJp2kReader(r io.Reader, options *DecodeJp2kOptions) (*VipsImage, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeJp2kBytes(b, options)
+}
+
+// DecodeJp2kStream reads r on demand, see DecodeStream.
+func DecodeJp2kStream(r io.Reader, options *DecodeJp2kOptions) (*VipsImage, error) {
+	source := newVipsSource(r)
+	defer C.g_object_unref(C.gpointer(source))
+	return decodeJp2kSource(source, options)
+}
+
+func decodeJp2kSource(source *C.VipsSource, options *DecodeJp2kOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &DecodeJp2kOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	var i *C.struct__VipsImage
+	if C.govips_jp2kload_source(source, &i, cOptions.Page, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return decoded(i, nil, options.DecodeOptions)
+}
+
+func DecodeJp2kBytes(b []byte, options *DecodeJp2kOptions) (*VipsImage, error) {
+	if len(b) == 0 {
+		return nil, ErrLoad
+	}
+	if options == nil {
+		options = &DecodeJp2kOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	var i *C.struct__VipsImage
+	if C.govips_jp2kload_buffer(unsafe.Pointer(&b[0]), C.size_t(len(b)), &i, cOptions.Page, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return decoded(i, b, options.DecodeOptions)
+}
+
+func DecodeJp2kFile(path string, options *DecodeJp2kOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &DecodeJp2kOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	cFileName := C.CString(path)
+	defer C.free(unsafe.Pointer(cFileName))
+	var i *C.struct__VipsImage
+	if C.govips_jp2kload(cFileName, &i, cOptions.Page, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return decoded(i, nil, options.DecodeOptions)
+}
+
+// DecodeJpegReader reads all of r into memory before decoding it.
+//
+// Deprecated: Use DecodeJpegStream, which reads r on demand instead of buffering it.
+func DecodeJpegReader(r io.Reader, options *DecodeJpegOptions) (*VipsImage, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeJpegBytes(b, options)
+}
+
+// DecodeJpegStream reads r on demand, see DecodeStream.
+func DecodeJpegStream(r io.Reader, options *DecodeJpegOptions) (*VipsImage, error) {
+	source := newVipsSource(r)
+	defer C.g_object_unref(C.gpointer(source))
+	return decodeJpegSource(source, options)
+}
+
+func decodeJpegSource(source *C.VipsSource, options *DecodeJpegOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &DecodeJpegOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	var i *C.struct__VipsImage
+	if C.govips_jpegload_source(source, &i, cOptions.Shrink, cOptions.Fail, cOptions.Autorotate, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return newVipsImage(i, nil), nil
+}
+
+func DecodeJpegBytes(b []byte, options *DecodeJpegOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &DecodeJpegOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	var i *C.struct__VipsImage
+	if C.govips_jpegload_buffer(unsafe.Pointer(&b[0]), C.size_t(len(b)), &i, cOptions.Shrink, cOptions.Fail, cOptions.Autorotate, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return newVipsImage(i, b), nil
+}
+
+func DecodeJpegFile(path string, options *DecodeJpegOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &DecodeJpegOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	cFileName := C.CString(path)
+	defer C.free(unsafe.Pointer(cFileName))
+	var i *C.struct__VipsImage
+	if C.govips_jpegload(cFileName, &i, cOptions.Shrink, cOptions.Fail, cOptions.Autorotate, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return newVipsImage(i, nil), nil
+}
+
+// DecodeJxlReader reads all of r into memory before decoding it.
+//
+// Deprecated: Use DecodeJxlStream, which reads r on demand instead of buffering it.
+func DecodeJxlReader(r io.Reader, options *DecodeOptions) (*VipsImage, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeJxlBytes(b, options)
+}
+
+// DecodeJxlStream reads r on demand, see DecodeStream.
+func DecodeJxlStream(r io.Reader, options *DecodeOptions) (*VipsImage, error) {
+	source := newVipsSource(r)
+	defer C.g_object_unref(C.gpointer(source))
+	return decodeJxlSource(source, options)
+}
+
+func decodeJxlSource(source *C.VipsSource, options *DecodeOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &DecodeOptions{}
+	}
+	cOptions := options.toC()
+	var i *C.struct__VipsImage
+	if C.govips_jxlload_source(source, &i, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return decoded(i, nil, *options)
+}
+
+func DecodeJxlBytes(b []byte, options *DecodeOptions) (*VipsImage, error) {
+	if len(b) == 0 {
+		return nil, ErrLoad
+	}
+	if options == nil {
+		options = &DecodeOptions{}
+	}
+	cOptions := options.toC()
+	var i *C.struct__VipsImage
+	if C.govips_jxlload_buffer(unsafe.Pointer(&b[0]), C.size_t(len(b)), &i, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return decoded(i, b, *options)
+}
+
+func DecodeJxlFile(path string, options *DecodeOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &DecodeOptions{}
+	}
+	cOptions := options.toC()
+	cFileName := C.CString(path)
+	defer C.free(unsafe.Pointer(cFileName))
+	var i *C.struct__VipsImage
+	if C.govips_jxlload(cFileName, &i, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return decoded(i, nil, *options)
+}
+
+// DecodeMagickReader reads all of r into memory before decoding it.
+//
+// Deprecated: Use DecodeMagickStream, which reads r on demand instead of buffering it.
+func DecodeMagickReader(r io.Reader, options *DecodeMagickOptions) (*VipsImage, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeMagickBytes(b, options)
+}
+
+// DecodeMagickStream reads r on demand, see DecodeStream.
+func DecodeMagickStream(r io.Reader, options *DecodeMagickOptions) (*VipsImage, error) {
+	source := newVipsSource(r)
+	defer C.g_object_unref(C.gpointer(source))
+	return decodeMagickSource(source, options)
+}
+
+func decodeMagickSource(source *C.VipsSource, options *DecodeMagickOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &DecodeMagickOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	var i *C.struct__VipsImage
+	if C.govips_magickload_source(source, &i, cOptions.AllFrames, cOptions.Density, cOptions.Page, cOptions.N, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return decoded(i, nil, options.DecodeOptions)
+}
+
+func DecodeMagickBytes(b []byte, options *DecodeMagickOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &DecodeMagickOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	var i *C.struct__VipsImage
+	if C.govips_magickload_buffer(unsafe.Pointer(&b[0]), C.size_t(len(b)), &i, cOptions.AllFrames, cOptions.Density, cOptions.Page, cOptions.N, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return decoded(i, b, options.DecodeOptions)
+}
+
+func DecodeMagickFile(path string, options *DecodeMagickOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &DecodeMagickOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	cFileName := C.CString(path)
+	defer C.free(unsafe.Pointer(cFileName))
+	var i *C.struct__VipsImage
+	if C.govips_magickload(cFileName, &i, cOptions.AllFrames, cOptions.Density, cOptions.Page, cOptions.N, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return decoded(i, nil, options.DecodeOptions)
+}
+
+// DecodePdfReader reads all of r into memory before decoding it.
+//
+// Deprecated: Use DecodePdfStream, which reads r on demand instead of buffering it.
+func DecodePdfReader(r io.Reader, options *DecodePdfOptions) (*VipsImage, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return DecodePdfBytes(b, options)
+}
+
+// DecodePdfStream reads r on demand, see DecodeStream.
+func DecodePdfStream(r io.Reader, options *DecodePdfOptions) (*VipsImage, error) {
+	source := newVipsSource(r)
+	defer C.g_object_unref(C.gpointer(source))
+	return decodePdfSource(source, options)
+}
+
+func decodePdfSource(source *C.VipsSource, options *DecodePdfOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &DecodePdfOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	var i *C.struct__VipsImage
+	if C.govips_pdfload_source(source, &i, cOptions.Page, cOptions.N, cOptions.Dpi, cOptions.Scale, cOptions.Background, cOptions.Password, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return decoded(i, nil, options.DecodeOptions)
+}
+
+func DecodePdfBytes(b []byte, options *DecodePdfOptions) (*VipsImage, error) {
+	if len(b) == 0 {
+		return nil, ErrLoad
+	}
+	if options == nil {
+		options = &DecodePdfOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	var i *C.struct__VipsImage
+	if C.govips_pdfload_buffer(unsafe.Pointer(&b[0]), C.size_t(len(b)), &i, cOptions.Page, cOptions.N, cOptions.Dpi, cOptions.Scale, cOptions.Background, cOptions.Password, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return decoded(i, b, options.DecodeOptions)
+}
+
+func DecodePdfFile(path string, options *DecodePdfOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &DecodePdfOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	cFileName := C.CString(path)
+	defer C.free(unsafe.Pointer(cFileName))
+	var i *C.struct__VipsImage
+	if C.govips_pdfload(cFileName, &i, cOptions.Page, cOptions.N, cOptions.Dpi, cOptions.Scale, cOptions.Background, cOptions.Password, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return decoded(i, nil, options.DecodeOptions)
+}
+
+// DecodePngReader reads all of r into memory before decoding it.
+//
+// Deprecated: Use DecodePngStream, which reads r on demand instead of buffering it.
+func DecodePngReader(r io.Reader, options *DecodeOptions) (*VipsImage, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return DecodePngBytes(b, options)
+}
+
+// DecodePngStream reads r on demand, see DecodeStream.
+func DecodePngStream(r io.Reader, options *DecodeOptions) (*VipsImage, error) {
+	source := newVipsSource(r)
+	defer C.g_object_unref(C.gpointer(source))
+	return decodePngSource(source, options)
+}
+
+func decodePngSource(source *C.VipsSource, options *DecodeOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &DecodeOptions{}
+	}
+	cOptions := options.toC()
+	var i *C.struct__VipsImage
+	if C.govips_pngload_source(source, &i, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return decoded(i, nil, *options)
+}
+
+func DecodePngBytes(b []byte, options *DecodeOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &DecodeOptions{}
+	}
+	cOptions := options.toC()
+	var i *C.struct__VipsImage
+	if C.govips_pngload_buffer(unsafe.Pointer(&b[0]), C.size_t(len(b)), &i, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return decoded(i, b, *options)
+}
+
+func DecodePngFile(path string, options *DecodeOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &DecodeOptions{}
+	}
+	cOptions := options.toC()
+	cFileName := C.CString(path)
+	defer C.free(unsafe.Pointer(cFileName))
+	var i *C.struct__VipsImage
+	if C.govips_pngload(cFileName, &i, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return decoded(i, nil, *options)
+}
+
+// DecodeSvgReader reads all of r into memory before decoding it.
+//
+// Deprecated: Use DecodeSvgStream, which reads r on demand instead of buffering it.
+func DecodeSvgReader(r io.Reader, options *DecodeSvgOptions) (*VipsImage, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeSvgBytes(b, options)
+}
+
+// DecodeSvgStream reads r on demand, see DecodeStream.
+func DecodeSvgStream(r io.Reader, options *DecodeSvgOptions) (*VipsImage, error) {
+	source := newVipsSource(r)
+	defer C.g_object_unref(C.gpointer(source))
+	return decodeSvgSource(source, options)
+}
+
+func decodeSvgSource(source *C.VipsSource, options *DecodeSvgOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &DecodeSvgOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	var i *C.struct__VipsImage
+	if C.govips_svgload_source(source, &i, cOptions.Dpi, cOptions.Scale, cOptions.Unlimited, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return decoded(i, nil, options.DecodeOptions)
+}
+
+func DecodeSvgBytes(b []byte, options *DecodeSvgOptions) (*VipsImage, error) {
+	if len(b) == 0 {
+		return nil, ErrLoad
+	}
+	if options == nil {
+		options = &DecodeSvgOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	var i *C.struct__VipsImage
+	if C.govips_svgload_buffer(unsafe.Pointer(&b[0]), C.size_t(len(b)), &i, cOptions.Dpi, cOptions.Scale, cOptions.Unlimited, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return decoded(i, b, options.DecodeOptions)
+}
+
+func DecodeSvgFile(path string, options *DecodeSvgOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &DecodeSvgOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	cFileName := C.CString(path)
+	defer C.free(unsafe.Pointer(cFileName))
+	var i *C.struct__VipsImage
+	if C.govips_svgload(cFileName, &i, cOptions.Dpi, cOptions.Scale, cOptions.Unlimited, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return decoded(i, nil, options.DecodeOptions)
+}
+
+// DecodeTiffReader reads all of r into memory before decoding it.
+//
+// Deprecated: Use DecodeTiffStream, which reads r on demand instead of buffering it.
+func DecodeTiffReader(r io.Reader, options *DecodeTiffOptions) (*VipsImage, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeTiffBytes(b, options)
+}
+
+// DecodeTiffStream reads r on demand, see DecodeStream.
+func DecodeTiffStream(r io.Reader, options *DecodeTiffOptions) (*VipsImage, error) {
+	source := newVipsSource(r)
+	defer C.g_object_unref(C.gpointer(source))
+	return decodeTiffSource(source, options)
+}
+
+func decodeTiffSource(source *C.VipsSource, options *DecodeTiffOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &DecodeTiffOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	var i *C.struct__VipsImage
+	if C.govips_tiffload_source(source, &i, cOptions.Page, cOptions.N, cOptions.Autorotate, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return newVipsImage(i, nil), nil
+}
+
+func DecodeTiffBytes(b []byte, options *DecodeTiffOptions) (*VipsImage, error) {
+	if len(b) == 0 {
+		return nil, ErrLoad
+	}
+	if options == nil {
+		options = &DecodeTiffOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	var i *C.struct__VipsImage
+	if C.govips_tiffload_buffer(unsafe.Pointer(&b[0]), C.size_t(len(b)), &i, cOptions.Page, cOptions.N, cOptions.Autorotate, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return newVipsImage(i, b), nil
+}
+
+func DecodeTiffFile(path string, options *DecodeTiffOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &DecodeTiffOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	cFileName := C.CString(path)
+	defer C.free(unsafe.Pointer(cFileName))
+	var i *C.struct__VipsImage
+	if C.govips_tiffload(cFileName, &i, cOptions.Page, cOptions.N, cOptions.Autorotate, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return newVipsImage(i, nil), nil
+}
+
+// DecodeWebpReader reads all of r into memory before decoding it.
+//
+// Deprecated: Use DecodeWebpStream, which reads r on demand instead of buffering it.
+func DecodeWebpReader(r io.Reader, options *DecodeWebpOptions) (*VipsImage, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeWebpBytes(b, options)
+}
+
+// DecodeWebpStream reads r on demand, see DecodeStream.
+func DecodeWebpStream(r io.Reader, options *DecodeWebpOptions) (*VipsImage, error) {
+	source := newVipsSource(r)
+	defer C.g_object_unref(C.gpointer(source))
+	return decodeWebpSource(source, options)
+}
+
+func decodeWebpSource(source *C.VipsSource, options *DecodeWebpOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &DecodeWebpOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	var i *C.struct__VipsImage
+	if C.govips_webpload_source(source, &i, cOptions.Shrink, cOptions.Page, cOptions.N, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return decoded(i, nil, options.DecodeOptions)
+}
+
+func DecodeWebpBytes(b []byte, options *DecodeWebpOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &DecodeWebpOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	var i *C.struct__VipsImage
+	if C.govips_webpload_buffer(unsafe.Pointer(&b[0]), C.size_t(len(b)), &i, cOptions.Shrink, cOptions.Page, cOptions.N, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return decoded(i, b, options.DecodeOptions)
+}
+
+func DecodeWebpFile(path string, options *DecodeWebpOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &DecodeWebpOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	cFileName := C.CString(path)
+	defer C.free(unsafe.Pointer(cFileName))
+	var i *C.struct__VipsImage
+	if C.govips_webpload(cFileName, &i, cOptions.Shrink, cOptions.Page, cOptions.N, cOptions.Access, cOptions.Disc) != 0 {
+		return nil, ErrLoad
+	}
+	return decoded(i, nil, options.DecodeOptions)
+}
+
+type DecodeFormatOptions struct {
+	Gif    *DecodeGifOptions
+	Heif   *DecodeHeifOptions
+	Jp2k   *DecodeJp2kOptions
+	Jpeg   *DecodeJpegOptions
+	Jxl    *DecodeOptions
+	Magick *DecodeMagickOptions
+	Pdf    *DecodePdfOptions
+	Png    *DecodeOptions
+	Svg    *DecodeSvgOptions
+	Tiff   *DecodeTiffOptions
+	Webp   *DecodeWebpOptions
+}
+
+// Decode reads all of r into memory before decoding it. Use DecodeStream to read r on demand instead.
+func Decode(r io.Reader, options *DecodeFormatOptions) (*VipsImage, string, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+	return DecodeBytes(b, options)
+}
+
+// DecodeStream pulls bytes from r through a libvips source as the pixels are needed instead of reading it up front, so
+// r must stay open and readable for as long as the returned image, or any image derived from it, is in use.
+func DecodeStream(r io.Reader, options *DecodeFormatOptions) (*VipsImage, string, error) {
+	if options == nil {
+		options = &DecodeFormatOptions{}
+	}
+	source := newVipsSource(r)
+	defer C.g_object_unref(C.gpointer(source))
+	loader := C.vips_foreign_find_load_source(source)
+	if loader == nil {
+		return nil, "", ErrFormat
+	}
+	format, err := formatFromLoader(C.GoString(loader))
+	if err != nil {
+		return nil, format, err
+	}
+	var i *VipsImage
+	switch format {
+	case FORMAT_GIF:
+		i, err = decodeGifSource(source, options.Gif)
+	case FORMAT_HEIF:
+		i, err = decodeHeifSource(source, options.Heif)
+		if err == nil {
+			format = heifFormat(i)
+		}
+	case FORMAT_JP2K:
+		i, err = decodeJp2kSource(source, options.Jp2k)
+	case FORMAT_JPEG:
+		i, err = decodeJpegSource(source, options.Jpeg)
+	case FORMAT_JXL:
+		i, err = decodeJxlSource(source, options.Jxl)
+	case FORMAT_MAGICK:
+		i, err = decodeMagickSource(source, options.Magick)
+	case FORMAT_PDF:
+		i, err = decodePdfSource(source, options.Pdf)
+	case FORMAT_PNG:
+		i, err = decodePngSource(source, options.Png)
+	case FORMAT_SVG:
+		i, err = decodeSvgSource(source, options.Svg)
+	case FORMAT_TIFF:
+		i, err = decodeTiffSource(source, options.Tiff)
+	case FORMAT_WEBP:
+		i, err = decodeWebpSource(source, options.Webp)
+	default:
+		err = ErrFormat
+	}
+	return i, format, err
+}
+
+func DecodeBytes(b []byte, options *DecodeFormatOptions) (*VipsImage, string, error) {
+	if options == nil {
+		options = &DecodeFormatOptions{}
+	}
+	format, err := DetectFormatBytes(b)
+	if err != nil {
+		return nil, format, err
+	}
+	var i *VipsImage
+	switch format {
+	case FORMAT_GIF:
+		i, err = DecodeGifBytes(b, options.Gif)
+	case FORMAT_HEIF:
+		i, err = DecodeHeifBytes(b, options.Heif)
+		if err == nil {
+			format = heifFormat(i)
+		}
+	case FORMAT_JP2K:
+		i, err = DecodeJp2kBytes(b, options.Jp2k)
+	case FORMAT_JPEG:
+		i, err = DecodeJpegBytes(b, options.Jpeg)
+	case FORMAT_JXL:
+		i, err = DecodeJxlBytes(b, options.Jxl)
+	case FORMAT_MAGICK:
+		i, err = DecodeMagickBytes(b, options.Magick)
+	case FORMAT_PDF:
+		i, err = DecodePdfBytes(b, options.Pdf)
+	case FORMAT_PNG:
+		i, err = DecodePngBytes(b, options.Png)
+	case FORMAT_SVG:
+		i, err = DecodeSvgBytes(b, options.Svg)
+	case FORMAT_TIFF:
+		i, err = DecodeTiffBytes(b, options.Tiff)
+	case FORMAT_WEBP:
+		i, err = DecodeWebpBytes(b, options.Webp)
+	default:
+		err = ErrFormat
+	}
+	return i, format, err
+}
+
+func DecodeFile(path string, options *DecodeFormatOptions) (*VipsImage, string, error) {
+	if options == nil {
+		options = &DecodeFormatOptions{}
+	}
+	cFileName := C.CString(path)
+	defer C.free(unsafe.Pointer(cFileName))
+	loader := C.vips_foreign_find_load(cFileName)
+	if loader == nil {
+		return nil, "", ErrFormat
+	}
+	format, err := formatFromLoader(C.GoString(loader))
+	if err != nil {
+		return nil, format, err
+	}
+	var i *VipsImage
+	switch format {
+	case FORMAT_GIF:
+		i, err = DecodeGifFile(path, options.Gif)
+	case FORMAT_HEIF:
+		i, err = DecodeHeifFile(path, options.Heif)
+		if err == nil {
+			format = heifFormat(i)
+		}
+	case FORMAT_JP2K:
+		i, err = DecodeJp2kFile(path, options.Jp2k)
+	case FORMAT_JPEG:
+		i, err = DecodeJpegFile(path, options.Jpeg)
+	case FORMAT_JXL:
+		i, err = DecodeJxlFile(path, options.Jxl)
+	case FORMAT_MAGICK:
+		i, err = DecodeMagickFile(path, options.Magick)
+	case FORMAT_PDF:
+		i, err = DecodePdfFile(path, options.Pdf)
+	case FORMAT_PNG:
+		i, err = DecodePngFile(path, options.Png)
+	case FORMAT_SVG:
+		i, err = DecodeSvgFile(path, options.Svg)
+	case FORMAT_TIFF:
+		i, err = DecodeTiffFile(path, options.Tiff)
+	case FORMAT_WEBP:
+		i, err = DecodeWebpFile(path, options.Webp)
+	default:
+		err = ErrFormat
+	}
+	return i, format, err
+}
+
+// AVIF shares the HEIF loader, so it is told apart by the compression the loader reports.
+func heifFormat(v *VipsImage) string {
+	compression := C.govips_get_string(v.cVipsImage, cHEIF_COMPRESSION)
+	if compression != nil && C.GoString(compression) == "av1" {
+		return FORMAT_AVIF
+	}
+	return FORMAT_HEIF
+}
+
+// DetectFormatBytes returns the format of b from the signature libvips sniffs to pick a loader, without reading the
+// header.
+func DetectFormatBytes(b []byte) (string, error) {
+	if len(b) == 0 {
+		return "", ErrLoad
+	}
+	loader := C.vips_foreign_find_load_buffer(unsafe.Pointer(&b[0]), C.size_t(len(b)))
+	if loader == nil {
+		return "", ErrFormat
+	}
+	return formatFromLoader(C.GoString(loader))
+}
+
+// The reader is only seeked when it implements io.Seeker, otherwise libvips buffers whatever it needs to rewind.
+func newVipsSource(r io.Reader) *C.VipsSource {
+	_, seekable := r.(io.Seeker)
+	return C.govips_source_new(C.int(registerStream(r)), toGBool(seekable))
+}
+
+func newVipsTarget(t *writerTarget) *C.VipsTarget {
+	return C.govips_target_new(C.int(registerStream(t)))
+}
+
+func formatFromLoader(loader string) (string, error) {
+	for _, l := range loaderFormats {
+		if strings.HasPrefix(loader, l.prefix) {
+			return l.format, nil
+		}
+	}
+	return "", ErrFormat
+}
+
+// Probe
+
+type Header struct {
+	Width          int
+	Height         int
+	Bands          int
+	Interpretation VipsInterpretation
+	Format         string
+	Orientation    int
+	HasProfile     bool
+}
+
+func (h Header) ColorModel() color.Model {
+	return colorModel(h.Interpretation, h.Bands)
+}
+
+func (h Header) Config() image.Config {
+	return image.Config{
+		ColorModel: h.ColorModel(),
+		Width:      h.Width,
+		Height:     h.Height,
+	}
+}
+
+// Probe reads only as much of r as the loader needs to parse the header, no pixels are decoded.
+func Probe(r io.Reader) (Header, error) {
+	vi, format, err := DecodeStream(r, nil)
+	if err != nil {
+		return Header{Format: format}, err
+	}
+	defer vi.Free()
+	return newHeader(vi, format), nil
+}
+
+func ProbeBytes(b []byte) (Header, error) {
+	vi, format, err := DecodeBytes(b, nil)
+	if err != nil {
+		return Header{Format: format}, err
+	}
+	defer vi.Free()
+	return newHeader(vi, format), nil
+}
+
+func ProbeFile(path string) (Header, error) {
+	vi, format, err := DecodeFile(path, nil)
+	if err != nil {
+		return Header{Format: format}, err
+	}
+	defer vi.Free()
+	return newHeader(vi, format), nil
+}
+
+// DecodeConfig is a drop in replacement for image.DecodeConfig.
+func DecodeConfig(r io.Reader) (image.Config, string, error) {
+	h, err := Probe(r)
+	if err != nil {
+		return image.Config{}, h.Format, err
+	}
+	return h.Config(), h.Format, nil
+}
+
+func newHeader(v *VipsImage, format string) Header {
+	bounds := v.Bounds()
+	return Header{
+		Width:          bounds.Dx(),
+		Height:         bounds.Dy(),
+		Bands:          v.Bands(),
+		Interpretation: v.Interpretation(),
+		Format:         format,
+		Orientation:    v.Orientation(),
+		HasProfile:     v.HasProfile(),
+	}
+}
+
+// Encode
+
+type EncodeGifOptions struct {
+	Dither   float64
+	Effort   int
+	Bitdepth int
+	Loop     int
+}
+
+func (o EncodeGifOptions) toC() cEncodeGifOptions {
+	if o.Dither == 0 {
+		o.Dither = 1
+	} else if o.Dither == FLOAT_ZERO {
+		o.Dither = 0
+	}
+	if o.Effort == 0 {
+		o.Effort = 7
+	}
+	if o.Bitdepth == 0 {
+		o.Bitdepth = 8
+	}
+	// A negative loop keeps the loop count of the image, zero loops forever.
+	if o.Loop == 0 {
+		o.Loop = -1
+	} else if o.Loop == INT_ZERO {
+		o.Loop = 0
+	}
+	return cEncodeGifOptions{
+		Dither:   C.gdouble(o.Dither),
+		Effort:   C.gint(o.Effort),
+		Bitdepth: C.gint(o.Bitdepth),
+		Loop:     C.gint(o.Loop),
+	}
+}
+
+type cEncodeGifOptions struct {
+	Dither   C.gdouble
+	Effort   C.gint
+	Bitdepth C.gint
+	Loop     C.gint
+}
+
+func (c *cEncodeGifOptions) Free() {
+}
+
+func EncodeGif(i *VipsImage, w io.Writer, options *EncodeGifOptions) error {
+	if options == nil {
+		options = &EncodeGifOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	t := &writerTarget{w: w}
+	target := newVipsTarget(t)
+	defer C.g_object_unref(C.gpointer(target))
+	if C.govips_gifsave_target(i.cVipsImage, target, cOptions.Dither, cOptions.Effort, cOptions.Bitdepth, cOptions.Loop) != 0 {
+		if t.err != nil {
+			return t.err
+		}
+		return ErrSave
+	}
+	return nil
 }
 
-func DecodeGifReader(r io.Reader, options *DecodeGifOptions) (*VipsImage, error) {
-	b, err := ioutil.ReadAll(r)
-	if err != nil {
-		return nil, err
-	}
-	return DecodeGifBytes(b, options)
+func EncodeGifFile(i *VipsImage, file *os.File, options *EncodeGifOptions) error {
+	return EncodeGif(i, file, options)
 }
 
-func DecodeGifBytes(b []byte, options *DecodeGifOptions) (*VipsImage, error) {
+func EncodeGifBytes(i *VipsImage, options *EncodeGifOptions) ([]byte, error) {
 	if options == nil {
-		options = &DecodeGifOptions{}
+		options = &EncodeGifOptions{}
 	}
 	cOptions := options.toC()
 	defer cOptions.Free()
-	var i *C.struct__VipsImage
-	if C.govips_gifload_buffer(unsafe.Pointer(&b[0]), C.size_t(len(b)), &i, cOptions.Page, cOptions.Access, cOptions.Disc) != 0 {
-		return nil, ErrLoad
+	var obuf unsafe.Pointer
+	olen := C.size_t(0)
+	if C.govips_gifsave_buffer(i.cVipsImage, &obuf, &olen, cOptions.Dither, cOptions.Effort, cOptions.Bitdepth, cOptions.Loop) != 0 {
+		return nil, ErrSave
 	}
-	return newVipsImage(i, b), nil
+	defer C.g_free(C.gpointer(obuf))
+	bytes := C.GoBytes(obuf, C.int(olen))
+	return bytes, nil
 }
 
-func DecodeJpegReader(r io.Reader, options *DecodeJpegOptions) (*VipsImage, error) {
-	b, err := ioutil.ReadAll(r)
-	if err != nil {
-		return nil, err
+type EncodeHeifOptions struct {
+	Q             int
+	Lossless      bool
+	Compression   HeifCompression
+	Effort        int
+	SubsampleMode SubsampleMode
+}
+
+func (o EncodeHeifOptions) toC() cEncodeHeifOptions {
+	if o.Q == 0 {
+		o.Q = 50
+	} else if o.Q == INT_ZERO {
+		o.Q = 0
+	}
+	if o.Effort == 0 {
+		o.Effort = 4
+	} else if o.Effort == INT_ZERO {
+		o.Effort = 0
+	}
+	return cEncodeHeifOptions{
+		Q:             C.gint(o.Q),
+		Lossless:      toGBool(o.Lossless),
+		Compression:   o.Compression.toC(),
+		Effort:        C.gint(o.Effort),
+		SubsampleMode: o.SubsampleMode.toC(),
 	}
-	return DecodeJpegBytes(b, options)
 }
 
-func DecodeJpegBytes(b []byte, options *DecodeJpegOptions) (*VipsImage, error) {
+type cEncodeHeifOptions struct {
+	Q             C.gint
+	Lossless      C.gboolean
+	Compression   C.VipsForeignHeifCompression
+	Effort        C.gint
+	SubsampleMode C.VipsForeignSubsample
+}
+
+func (c *cEncodeHeifOptions) Free() {
+}
+
+func EncodeHeif(i *VipsImage, w io.Writer, options *EncodeHeifOptions) error {
 	if options == nil {
-		options = &DecodeJpegOptions{}
+		options = &EncodeHeifOptions{}
 	}
 	cOptions := options.toC()
 	defer cOptions.Free()
-	var i *C.struct__VipsImage
-	if C.govips_jpegload_buffer(unsafe.Pointer(&b[0]), C.size_t(len(b)), &i, cOptions.Shrink, cOptions.Fail, cOptions.Autorotate, cOptions.Access, cOptions.Disc) != 0 {
-		return nil, ErrLoad
+	t := &writerTarget{w: w}
+	target := newVipsTarget(t)
+	defer C.g_object_unref(C.gpointer(target))
+	if C.govips_heifsave_target(i.cVipsImage, target, cOptions.Q, cOptions.Lossless, cOptions.Compression, cOptions.Effort, cOptions.SubsampleMode) != 0 {
+		if t.err != nil {
+			return t.err
+		}
+		return ErrSave
 	}
-	return newVipsImage(i, b), nil
+	return nil
 }
 
-func DecodeMagickReader(r io.Reader, options *DecodeMagickOptions) (*VipsImage, error) {
-	b, err := ioutil.ReadAll(r)
-	if err != nil {
-		return nil, err
-	}
-	return DecodeMagickBytes(b, options)
+func EncodeHeifFile(i *VipsImage, file *os.File, options *EncodeHeifOptions) error {
+	return EncodeHeif(i, file, options)
 }
 
-func DecodeMagickBytes(b []byte, options *DecodeMagickOptions) (*VipsImage, error) {
+func EncodeHeifBytes(i *VipsImage, options *EncodeHeifOptions) ([]byte, error) {
 	if options == nil {
-		options = &DecodeMagickOptions{}
+		options = &EncodeHeifOptions{}
 	}
 	cOptions := options.toC()
 	defer cOptions.Free()
-	var i *C.struct__VipsImage
-	if C.govips_magickload_buffer(unsafe.Pointer(&b[0]), C.size_t(len(b)), &i, cOptions.AllFrames, cOptions.Density, cOptions.Page, cOptions.Access, cOptions.Disc) != 0 {
-		return nil, ErrLoad
+	var obuf unsafe.Pointer
+	olen := C.size_t(0)
+	if C.govips_heifsave_buffer(i.cVipsImage, &obuf, &olen, cOptions.Q, cOptions.Lossless, cOptions.Compression, cOptions.Effort, cOptions.SubsampleMode) != 0 {
+		return nil, ErrSave
 	}
-	return newVipsImage(i, b), nil
+	defer C.g_free(C.gpointer(obuf))
+	bytes := C.GoBytes(obuf, C.int(olen))
+	return bytes, nil
 }
 
-func DecodePngReader(r io.Reader, options *DecodeOptions) (*VipsImage, error) {
-	b, err := ioutil.ReadAll(r)
-	if err != nil {
-		return nil, err
+type EncodeJp2kOptions struct {
+	Q             int
+	Lossless      bool
+	TileWidth     int
+	TileHeight    int
+	SubsampleMode SubsampleMode
+}
+
+func (o EncodeJp2kOptions) toC() cEncodeJp2kOptions {
+	if o.Q == 0 {
+		o.Q = 48
+	} else if o.Q == INT_ZERO {
+		o.Q = 0
+	}
+	if o.TileWidth == 0 {
+		o.TileWidth = 512
+	}
+	if o.TileHeight == 0 {
+		o.TileHeight = 512
+	}
+	return cEncodeJp2kOptions{
+		Q:             C.gint(o.Q),
+		Lossless:      toGBool(o.Lossless),
+		TileWidth:     C.gint(o.TileWidth),
+		TileHeight:    C.gint(o.TileHeight),
+		SubsampleMode: o.SubsampleMode.toC(),
 	}
-	return DecodePngBytes(b, options)
 }
 
-func DecodePngBytes(b []byte, options *DecodeOptions) (*VipsImage, error) {
+type cEncodeJp2kOptions struct {
+	Q             C.gint
+	Lossless      C.gboolean
+	TileWidth     C.gint
+	TileHeight    C.gint
+	SubsampleMode C.VipsForeignSubsample
+}
+
+func (c *cEncodeJp2kOptions) Free() {
+}
+
+func EncodeJp2k(i *VipsImage, w io.Writer, options *EncodeJp2kOptions) error {
 	if options == nil {
-		options = &DecodeOptions{}
+		options = &EncodeJp2kOptions{}
 	}
 	cOptions := options.toC()
-	var i *C.struct__VipsImage
-	if C.govips_pngload_buffer(unsafe.Pointer(&b[0]), C.size_t(len(b)), &i, cOptions.Access, cOptions.Disc) != 0 {
-		return nil, ErrLoad
+	defer cOptions.Free()
+	t := &writerTarget{w: w}
+	target := newVipsTarget(t)
+	defer C.g_object_unref(C.gpointer(target))
+	if C.govips_jp2ksave_target(i.cVipsImage, target, cOptions.Q, cOptions.Lossless, cOptions.TileWidth, cOptions.TileHeight, cOptions.SubsampleMode) != 0 {
+		if t.err != nil {
+			return t.err
+		}
+		return ErrSave
 	}
-	return newVipsImage(i, b), nil
+	return nil
 }
 
-func DecodeWebpReader(r io.Reader, options *DecodeWebpOptions) (*VipsImage, error) {
-	b, err := ioutil.ReadAll(r)
-	if err != nil {
-		return nil, err
-	}
-	return DecodeWebpBytes(b, options)
+func EncodeJp2kFile(i *VipsImage, file *os.File, options *EncodeJp2kOptions) error {
+	return EncodeJp2k(i, file, options)
 }
 
-func DecodeWebpBytes(b []byte, options *DecodeWebpOptions) (*VipsImage, error) {
+func EncodeJp2kBytes(i *VipsImage, options *EncodeJp2kOptions) ([]byte, error) {
 	if options == nil {
-		options = &DecodeWebpOptions{}
+		options = &EncodeJp2kOptions{}
 	}
 	cOptions := options.toC()
 	defer cOptions.Free()
-	var i *C.struct__VipsImage
-	if C.govips_webpload_buffer(unsafe.Pointer(&b[0]), C.size_t(len(b)), &i, cOptions.Shrink, cOptions.Access, cOptions.Disc) != 0 {
-		return nil, ErrLoad
+	var obuf unsafe.Pointer
+	olen := C.size_t(0)
+	if C.govips_jp2ksave_buffer(i.cVipsImage, &obuf, &olen, cOptions.Q, cOptions.Lossless, cOptions.TileWidth, cOptions.TileHeight, cOptions.SubsampleMode) != 0 {
+		return nil, ErrSave
 	}
-	return newVipsImage(i, b), nil
+	defer C.g_free(C.gpointer(obuf))
+	bytes := C.GoBytes(obuf, C.int(olen))
+	return bytes, nil
 }
 
-// Encode
-
 type EncodeJpegOptions struct {
 	Q                   int
 	Profile             string
@@ -742,20 +2932,28 @@ func (c *cEncodeJpegOptions) Free() {
 	}
 }
 
-func EncodeJpegFile(i *VipsImage, file *os.File, options *EncodeJpegOptions) error {
+func EncodeJpeg(i *VipsImage, w io.Writer, options *EncodeJpegOptions) error {
 	if options == nil {
 		options = &EncodeJpegOptions{}
 	}
 	cOptions := options.toC()
 	defer cOptions.Free()
-	cFileName := C.CString(file.Name())
-	defer C.free(unsafe.Pointer(cFileName))
-	if C.govips_jpegsave(i.cVipsImage, cFileName, cOptions.Q, cOptions.Profile, cOptions.OptimizeCoding, cOptions.Interlace, cOptions.Strip, cOptions.NoSubsample, cOptions.TrellisQuantization, cOptions.OvershootDeringing, cOptions.OptimizeScans, cOptions.QuantizationTable) != 0 {
+	t := &writerTarget{w: w}
+	target := newVipsTarget(t)
+	defer C.g_object_unref(C.gpointer(target))
+	if C.govips_jpegsave_target(i.cVipsImage, target, cOptions.Q, cOptions.Profile, cOptions.OptimizeCoding, cOptions.Interlace, cOptions.Strip, cOptions.NoSubsample, cOptions.TrellisQuantization, cOptions.OvershootDeringing, cOptions.OptimizeScans, cOptions.QuantizationTable) != 0 {
+		if t.err != nil {
+			return t.err
+		}
 		return ErrSave
 	}
 	return nil
 }
 
+func EncodeJpegFile(i *VipsImage, file *os.File, options *EncodeJpegOptions) error {
+	return EncodeJpeg(i, file, options)
+}
+
 func EncodeJpegBytes(i *VipsImage, options *EncodeJpegOptions) ([]byte, error) {
 	if options == nil {
 		options = &EncodeJpegOptions{}
@@ -772,6 +2970,82 @@ func EncodeJpegBytes(i *VipsImage, options *EncodeJpegOptions) ([]byte, error) {
 	return bytes, nil
 }
 
+type EncodeJxlOptions struct {
+	Distance float64
+	Q        int
+	Effort   int
+	Lossless bool
+	Tier     int
+}
+
+func (o EncodeJxlOptions) toC() cEncodeJxlOptions {
+	// A positive distance takes precedence over Q, mathematically lossless output is requested with Lossless instead.
+	if o.Q == 0 {
+		o.Q = 75
+	} else if o.Q == INT_ZERO {
+		o.Q = 0
+	}
+	if o.Effort == 0 {
+		o.Effort = 7
+	}
+	return cEncodeJxlOptions{
+		Distance: C.gdouble(o.Distance),
+		Q:        C.gint(o.Q),
+		Effort:   C.gint(o.Effort),
+		Lossless: toGBool(o.Lossless),
+		Tier:     C.gint(o.Tier),
+	}
+}
+
+type cEncodeJxlOptions struct {
+	Distance C.gdouble
+	Q        C.gint
+	Effort   C.gint
+	Lossless C.gboolean
+	Tier     C.gint
+}
+
+func (c *cEncodeJxlOptions) Free() {
+}
+
+func EncodeJxl(i *VipsImage, w io.Writer, options *EncodeJxlOptions) error {
+	if options == nil {
+		options = &EncodeJxlOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	t := &writerTarget{w: w}
+	target := newVipsTarget(t)
+	defer C.g_object_unref(C.gpointer(target))
+	if C.govips_jxlsave_target(i.cVipsImage, target, cOptions.Distance, cOptions.Q, cOptions.Effort, cOptions.Lossless, cOptions.Tier) != 0 {
+		if t.err != nil {
+			return t.err
+		}
+		return ErrSave
+	}
+	return nil
+}
+
+func EncodeJxlFile(i *VipsImage, file *os.File, options *EncodeJxlOptions) error {
+	return EncodeJxl(i, file, options)
+}
+
+func EncodeJxlBytes(i *VipsImage, options *EncodeJxlOptions) ([]byte, error) {
+	if options == nil {
+		options = &EncodeJxlOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	var obuf unsafe.Pointer
+	olen := C.size_t(0)
+	if C.govips_jxlsave_buffer(i.cVipsImage, &obuf, &olen, cOptions.Distance, cOptions.Q, cOptions.Effort, cOptions.Lossless, cOptions.Tier) != 0 {
+		return nil, ErrSave
+	}
+	defer C.g_free(C.gpointer(obuf))
+	bytes := C.GoBytes(obuf, C.int(olen))
+	return bytes, nil
+}
+
 type EncodePngOptions struct {
 	Compression int
 	Interlace   bool
@@ -813,20 +3087,28 @@ func (c *cEncodePngOptions) Free() {
 	}
 }
 
-func EncodePngFile(i *VipsImage, file *os.File, options *EncodePngOptions) error {
+func EncodePng(i *VipsImage, w io.Writer, options *EncodePngOptions) error {
 	if options == nil {
 		options = &EncodePngOptions{}
 	}
 	cOptions := options.toC()
 	defer cOptions.Free()
-	cFileName := C.CString(file.Name())
-	defer C.free(unsafe.Pointer(cFileName))
-	if C.govips_pngsave(i.cVipsImage, cFileName, cOptions.Compression, cOptions.Interlace, cOptions.Profile, cOptions.Filter) != 0 {
+	t := &writerTarget{w: w}
+	target := newVipsTarget(t)
+	defer C.g_object_unref(C.gpointer(target))
+	if C.govips_pngsave_target(i.cVipsImage, target, cOptions.Compression, cOptions.Interlace, cOptions.Profile, cOptions.Filter) != 0 {
+		if t.err != nil {
+			return t.err
+		}
 		return ErrSave
 	}
 	return nil
 }
 
+func EncodePngFile(i *VipsImage, file *os.File, options *EncodePngOptions) error {
+	return EncodePng(i, file, options)
+}
+
 func EncodePngBytes(i *VipsImage, options *EncodePngOptions) ([]byte, error) {
 	if options == nil {
 		options = &EncodePngOptions{}
@@ -843,6 +3125,108 @@ func EncodePngBytes(i *VipsImage, options *EncodePngOptions) ([]byte, error) {
 	return bytes, nil
 }
 
+type EncodeTiffOptions struct {
+	Compression TiffCompression
+	Q           int
+	Predictor   TiffPredictor
+	Profile     string
+	Tile        bool
+	TileWidth   int
+	TileHeight  int
+	Pyramid     bool
+	BigTiff     bool
+}
+
+func (o EncodeTiffOptions) toC() cEncodeTiffOptions {
+	if o.Q == 0 {
+		o.Q = 75
+	} else if o.Q == INT_ZERO {
+		o.Q = 0
+	}
+	var profile *C.char
+	if o.Profile == STRING_ZERO {
+		profile = C.CString("")
+	} else if o.Profile != "" {
+		profile = C.CString(o.Profile)
+	}
+	if o.TileWidth == 0 {
+		o.TileWidth = 128
+	}
+	if o.TileHeight == 0 {
+		o.TileHeight = 128
+	}
+	return cEncodeTiffOptions{
+		Compression: o.Compression.toC(),
+		Q:           C.gint(o.Q),
+		Predictor:   o.Predictor.toC(),
+		Profile:     profile,
+		Tile:        toGBool(o.Tile),
+		TileWidth:   C.gint(o.TileWidth),
+		TileHeight:  C.gint(o.TileHeight),
+		Pyramid:     toGBool(o.Pyramid),
+		BigTiff:     toGBool(o.BigTiff),
+	}
+}
+
+type cEncodeTiffOptions struct {
+	Compression C.VipsForeignTiffCompression
+	Q           C.gint
+	Predictor   C.VipsForeignTiffPredictor
+	Profile     *C.char
+	Tile        C.gboolean
+	TileWidth   C.gint
+	TileHeight  C.gint
+	Pyramid     C.gboolean
+	BigTiff     C.gboolean
+}
+
+func (c *cEncodeTiffOptions) Free() {
+	if c.Profile != nil {
+		C.free(unsafe.Pointer(c.Profile))
+		c.Profile = nil
+	}
+}
+
+// EncodeTiff writes the TIFF to w through a custom target. libtiff needs to seek while writing, so libvips builds the
+// whole file in memory and copies it to w once it is complete, which lets w be a pipe.
+func EncodeTiff(i *VipsImage, w io.Writer, options *EncodeTiffOptions) error {
+	if options == nil {
+		options = &EncodeTiffOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	t := &writerTarget{w: w}
+	target := newVipsTarget(t)
+	defer C.g_object_unref(C.gpointer(target))
+	if C.govips_tiffsave_target(i.cVipsImage, target, cOptions.Compression, cOptions.Q, cOptions.Predictor, cOptions.Profile, cOptions.Tile, cOptions.TileWidth, cOptions.TileHeight, cOptions.Pyramid, cOptions.BigTiff) != 0 {
+		if t.err != nil {
+			return t.err
+		}
+		return ErrSave
+	}
+	return nil
+}
+
+func EncodeTiffFile(i *VipsImage, file *os.File, options *EncodeTiffOptions) error {
+	return EncodeTiff(i, file, options)
+}
+
+func EncodeTiffBytes(i *VipsImage, options *EncodeTiffOptions) ([]byte, error) {
+	if options == nil {
+		options = &EncodeTiffOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	var obuf unsafe.Pointer
+	olen := C.size_t(0)
+	if C.govips_tiffsave_buffer(i.cVipsImage, &obuf, &olen, cOptions.Compression, cOptions.Q, cOptions.Predictor, cOptions.Profile, cOptions.Tile, cOptions.TileWidth, cOptions.TileHeight, cOptions.Pyramid, cOptions.BigTiff) != 0 {
+		return nil, ErrSave
+	}
+	defer C.g_free(C.gpointer(obuf))
+	bytes := C.GoBytes(obuf, C.int(olen))
+	return bytes, nil
+}
+
 type EncodeWebpOptions struct {
 	Q              int
 	Lossless       bool
@@ -850,9 +3234,17 @@ type EncodeWebpOptions struct {
 	SmartSubsample bool
 	NearLossless   bool
 	AlphaQ         int
+	KMin           int
+	KMax           int
+	MinSize        bool
+	// KeepMetadata keeps the EXIF, XMP and ICC metadata, which is stripped by default.
+	KeepMetadata bool
+	// Delay is either empty, a single delay for every frame or one delay per frame, in milliseconds.
+	Delay []int
+	Loop  int
 }
 
-func (o EncodeWebpOptions) toC() cEncodeWebpOptions {
+func (o EncodeWebpOptions) toC(frames int) (cEncodeWebpOptions, error) {
 	if o.Q == 0 {
 		o.Q = 75
 	} else if o.Q == INT_ZERO {
@@ -861,6 +3253,35 @@ func (o EncodeWebpOptions) toC() cEncodeWebpOptions {
 	if o.AlphaQ == 0 {
 		o.AlphaQ = 100
 	}
+	if o.KMin == 0 {
+		o.KMin = math.MaxInt32 - 1
+	} else if o.KMin == INT_ZERO {
+		o.KMin = 0
+	}
+	if o.KMax == 0 {
+		o.KMax = math.MaxInt32
+	} else if o.KMax == INT_ZERO {
+		o.KMax = 0
+	}
+	// A negative loop keeps the loop count of the image, zero loops forever.
+	if o.Loop == 0 {
+		o.Loop = -1
+	} else if o.Loop == INT_ZERO {
+		o.Loop = 0
+	}
+	if len(o.Delay) == 1 && frames > 1 {
+		d := o.Delay[0]
+		o.Delay = make([]int, frames)
+		for i := range o.Delay {
+			o.Delay[i] = d
+		}
+	} else if len(o.Delay) > 1 && len(o.Delay) != frames {
+		return cEncodeWebpOptions{}, fmt.Errorf("Invalid number of delays: %d for %d frames", len(o.Delay), frames)
+	}
+	var delay *C.int
+	if len(o.Delay) > 0 {
+		delay = newCIntArray(o.Delay)
+	}
 	return cEncodeWebpOptions{
 		Q:              C.gint(o.Q),
 		Lossless:       toGBool(o.Lossless),
@@ -868,7 +3289,14 @@ func (o EncodeWebpOptions) toC() cEncodeWebpOptions {
 		SmartSubsample: toGBool(o.SmartSubsample),
 		NearLossless:   toGBool(o.NearLossless),
 		AlphaQ:         C.gint(o.AlphaQ),
-	}
+		KMin:           C.gint(o.KMin),
+		KMax:           C.gint(o.KMax),
+		MinSize:        toGBool(o.MinSize),
+		Strip:          toGBool(!o.KeepMetadata),
+		Delay:          delay,
+		NDelay:         C.int(len(o.Delay)),
+		Loop:           C.gint(o.Loop),
+	}, nil
 }
 
 type cEncodeWebpOptions struct {
@@ -878,34 +3306,59 @@ type cEncodeWebpOptions struct {
 	SmartSubsample C.gboolean
 	NearLossless   C.gboolean
 	AlphaQ         C.gint
+	KMin           C.gint
+	KMax           C.gint
+	MinSize        C.gboolean
+	Strip          C.gboolean
+	Delay          *C.int
+	NDelay         C.int
+	Loop           C.gint
 }
 
 func (c *cEncodeWebpOptions) Free() {
+	if c.Delay != nil {
+		C.free(unsafe.Pointer(c.Delay))
+		c.Delay = nil
+	}
 }
 
-func EncodeWebpFile(i *VipsImage, file *os.File, options *EncodeWebpOptions) error {
+func EncodeWebp(i *VipsImage, w io.Writer, options *EncodeWebpOptions) error {
 	if options == nil {
 		options = &EncodeWebpOptions{}
 	}
-	cOptions := options.toC()
+	cOptions, err := options.toC(i.Bounds().Dy() / frameSize(i).Y)
+	if err != nil {
+		return err
+	}
 	defer cOptions.Free()
-	cFileName := C.CString(file.Name())
-	defer C.free(unsafe.Pointer(cFileName))
-	if C.govips_webpsave(i.cVipsImage, cFileName, cOptions.Q, cOptions.Lossless, cOptions.Preset, cOptions.SmartSubsample, cOptions.NearLossless, cOptions.AlphaQ) != 0 {
+	t := &writerTarget{w: w}
+	target := newVipsTarget(t)
+	defer C.g_object_unref(C.gpointer(target))
+	if C.govips_webpsave_target(i.cVipsImage, target, cOptions.Q, cOptions.Lossless, cOptions.Preset, cOptions.SmartSubsample, cOptions.NearLossless, cOptions.AlphaQ, cOptions.KMin, cOptions.KMax, cOptions.MinSize, cOptions.Strip, cOptions.Delay, cOptions.NDelay, cOptions.Loop) != 0 {
+		if t.err != nil {
+			return t.err
+		}
 		return ErrSave
 	}
 	return nil
 }
 
+func EncodeWebpFile(i *VipsImage, file *os.File, options *EncodeWebpOptions) error {
+	return EncodeWebp(i, file, options)
+}
+
 func EncodeWebpBytes(i *VipsImage, options *EncodeWebpOptions) ([]byte, error) {
 	if options == nil {
 		options = &EncodeWebpOptions{}
 	}
-	cOptions := options.toC()
+	cOptions, err := options.toC(i.Bounds().Dy() / frameSize(i).Y)
+	if err != nil {
+		return nil, err
+	}
 	defer cOptions.Free()
 	var obuf unsafe.Pointer
 	olen := C.size_t(0)
-	if C.govips_webpsave_buffer(i.cVipsImage, &obuf, &olen, cOptions.Q, cOptions.Lossless, cOptions.Preset, cOptions.SmartSubsample, cOptions.NearLossless, cOptions.AlphaQ) != 0 {
+	if C.govips_webpsave_buffer(i.cVipsImage, &obuf, &olen, cOptions.Q, cOptions.Lossless, cOptions.Preset, cOptions.SmartSubsample, cOptions.NearLossless, cOptions.AlphaQ, cOptions.KMin, cOptions.KMax, cOptions.MinSize, cOptions.Strip, cOptions.Delay, cOptions.NDelay, cOptions.Loop) != 0 {
 		return nil, ErrSave
 	}
 	defer C.g_free(C.gpointer(obuf))
@@ -953,19 +3406,83 @@ func Embed(v *VipsImage, x, y, width, height int, options *EmbedOptions) (*VipsI
 	if C.govips_embed(v.cVipsImage, &i, C.int(x), C.int(y), C.int(width), C.int(height), cOptions.Extend, cOptions.Background) != 0 {
 		return nil, ErrEmbed
 	}
-	return newVipsImage(i, v.goBytes), nil
+	return newVipsImage(i, v.goBytes), nil
+}
+
+func ExtractArea(v *VipsImage, left, top, width, height int) (*VipsImage, error) {
+	var i *C.struct__VipsImage
+	if C.govips_extract_area(v.cVipsImage, &i, C.int(left), C.int(top), C.int(width), C.int(height)) != 0 {
+		return nil, ErrCrop
+	}
+	return newVipsImage(i, v.goBytes), nil
+}
+
+func Crop(v *VipsImage, left, top, width, height int) (*VipsImage, error) {
+	return ExtractArea(v, left, top, width, height)
+}
+
+// EmbedFrames is Embed applied to every frame, see MapFrames.
+func EmbedFrames(v *VipsImage, x, y, width, height int, options *EmbedOptions) (*VipsImage, error) {
+	return MapFrames(v, func(frame *VipsImage) (*VipsImage, error) {
+		return Embed(frame, x, y, width, height, options)
+	})
+}
+
+// ExtractAreaFrames is ExtractArea applied to every frame, see MapFrames.
+func ExtractAreaFrames(v *VipsImage, left, top, width, height int) (*VipsImage, error) {
+	return MapFrames(v, func(frame *VipsImage) (*VipsImage, error) {
+		return ExtractArea(frame, left, top, width, height)
+	})
+}
+
+// CropGravity crops every frame to width x height, keeping the side or corner given by gravity. A size larger than
+// the frame is clamped to it, a size of zero or less is an error. The chosen rectangle is returned with the image.
+func CropGravity(v *VipsImage, width, height int, gravity Gravity) (*VipsImage, image.Rectangle, error) {
+	if width <= 0 || height <= 0 {
+		return nil, image.ZR, ErrCrop
+	}
+	size := frameSize(v)
+	r := gravity.rect(size, clampInt(width, 1, size.X), clampInt(height, 1, size.Y))
+	i, err := ExtractAreaFrames(v, r.Min.X, r.Min.Y, r.Dx(), r.Dy())
+	if err != nil {
+		return nil, image.ZR, err
+	}
+	return i, r, nil
 }
 
-func ExtractArea(v *VipsImage, left, top, width, height int) (*VipsImage, error) {
+// SmartCrop crops to width x height, choosing the rectangle by interesting, for example VIPS_INTERESTING_ATTENTION
+// or VIPS_INTERESTING_ENTROPY. The rectangle of an animation is chosen from its first frame and applied to every frame.
+func SmartCrop(v *VipsImage, width, height int, interesting VipsInteresting) (*VipsImage, image.Rectangle, error) {
+	if width <= 0 || height <= 0 {
+		return nil, image.ZR, ErrCrop
+	}
+	size := frameSize(v)
+	width, height = clampInt(width, 1, size.X), clampInt(height, 1, size.Y)
+	frame := v
+	if size.Y < v.Bounds().Dy() {
+		first, err := ExtractArea(v, 0, 0, size.X, size.Y)
+		if err != nil {
+			return nil, image.ZR, err
+		}
+		defer first.Free()
+		frame = first
+	}
 	var i *C.struct__VipsImage
-	if C.govips_extract_area(v.cVipsImage, &i, C.int(left), C.int(top), C.int(width), C.int(height)) != 0 {
-		return nil, ErrCrop
+	var left, top C.int
+	if C.govips_smartcrop(frame.cVipsImage, &i, C.int(width), C.int(height), interesting.toC(), &left, &top) != 0 {
+		return nil, image.ZR, ErrCrop
 	}
-	return newVipsImage(i, v.goBytes), nil
-}
-
-func Crop(v *VipsImage, left, top, width, height int) (*VipsImage, error) {
-	return ExtractArea(v, left, top, width, height)
+	r := image.Rect(int(left), int(top), int(left)+width, int(top)+height)
+	cropped := newVipsImage(i, v.goBytes)
+	if frame == v {
+		return cropped, r, nil
+	}
+	cropped.Free()
+	cropped, err := ExtractAreaFrames(v, r.Min.X, r.Min.Y, width, height)
+	if err != nil {
+		return nil, image.ZR, err
+	}
+	return cropped, r, nil
 }
 
 func Shrink(v *VipsImage, xshrink, yshrink float64) (*VipsImage, error) {
@@ -1024,6 +3541,214 @@ func Resize(v *VipsImage, scale, vscale float64, kernel VipsKernel) (*VipsImage,
 	return newVipsImage(i, v.goBytes), nil
 }
 
+// ResizeFrames is Resize applied to every frame, see MapFrames.
+func ResizeFrames(v *VipsImage, scale, vscale float64, kernel VipsKernel) (*VipsImage, error) {
+	return MapFrames(v, func(frame *VipsImage) (*VipsImage, error) {
+		return Resize(frame, scale, vscale, kernel)
+	})
+}
+
+type ResizeOptions struct {
+	Fit                ResizeFit
+	Gravity            Gravity
+	Kernel             VipsKernel
+	Background         []float64
+	WithoutEnlargement bool
+}
+
+func (o ResizeOptions) kernel() VipsKernel {
+	if o.Kernel == 0 {
+		return VIPS_KERNEL_LANCZOS3
+	} else if o.Kernel == INT_ZERO {
+		return VIPS_KERNEL_NEAREST
+	}
+	return o.Kernel
+}
+
+// ResizeTo resizes every frame to width x height according to the fit. A zero width or height is derived from the
+// other dimension, preserving the aspect ratio.
+func ResizeTo(v *VipsImage, width, height int, options *ResizeOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &ResizeOptions{}
+	}
+	if width <= 0 && height <= 0 {
+		return nil, ErrResize
+	}
+	size := frameSize(v)
+	hscale := float64(width) / float64(size.X)
+	vscale := float64(height) / float64(size.Y)
+	if width <= 0 {
+		hscale = vscale
+	} else if height <= 0 {
+		vscale = hscale
+	}
+	switch options.Fit {
+	case FitInside, FitContain:
+		hscale = math.Min(hscale, vscale)
+		vscale = hscale
+	case FitCover, FitOutside:
+		hscale = math.Max(hscale, vscale)
+		vscale = hscale
+	}
+	if options.WithoutEnlargement {
+		hscale = math.Min(hscale, 1)
+		vscale = math.Min(vscale, 1)
+	}
+	var resized *VipsImage
+	var err error
+	if hscale == 1 && vscale == 1 {
+		resized, err = copyImage(v)
+	} else {
+		resized, err = ResizeFrames(v, hscale, vscale, options.kernel())
+	}
+	if err != nil {
+		return nil, err
+	}
+	size = frameSize(resized)
+	if width <= 0 {
+		width = size.X
+	}
+	if height <= 0 {
+		height = size.Y
+	}
+	switch options.Fit {
+	case FitCover:
+		// Without enlargement the image may already be smaller than the box, so only crop what overflows.
+		w, h := width, height
+		if w > size.X {
+			w = size.X
+		}
+		if h > size.Y {
+			h = size.Y
+		}
+		if w == size.X && h == size.Y {
+			return resized, nil
+		}
+		defer resized.Free()
+		r := options.Gravity.rect(size, w, h)
+		return ExtractAreaFrames(resized, r.Min.X, r.Min.Y, w, h)
+	case FitContain:
+		if width == size.X && height == size.Y {
+			return resized, nil
+		}
+		defer resized.Free()
+		return EmbedFrames(resized, (width-size.X)/2, (height-size.Y)/2, width, height, &EmbedOptions{
+			Extend:     VIPS_EXTEND_BACKGROUND,
+			Background: options.Background,
+		})
+	}
+	return resized, nil
+}
+
+type ThumbnailOptions struct {
+	Size          VipsSize
+	Crop          VipsInteresting
+	Linear        bool
+	NoRotate      bool
+	ImportProfile string
+	ExportProfile string
+	// Intent is only passed when set, so zero keeps the libvips default of relative. Use INT_ZERO for perceptual.
+	Intent VipsIntent
+	// OptionString is passed to the loader by Thumbnail, for example "n=-1" to thumbnail every frame of an animation.
+	OptionString string
+}
+
+func (o ThumbnailOptions) toC() cThumbnailOptions {
+	var importProfile, exportProfile *C.char
+	if o.ImportProfile != "" {
+		importProfile = C.CString(o.ImportProfile)
+	}
+	if o.ExportProfile != "" {
+		exportProfile = C.CString(o.ExportProfile)
+	}
+	intent := C.int(-1)
+	if o.Intent == INT_ZERO {
+		intent = C.int(C.VIPS_INTENT_PERCEPTUAL)
+	} else if o.Intent != 0 {
+		intent = C.int(o.Intent.toC())
+	}
+	return cThumbnailOptions{
+		Size:          o.Size.toC(),
+		Crop:          o.Crop.toC(),
+		Linear:        toGBool(o.Linear),
+		NoRotate:      toGBool(o.NoRotate),
+		ImportProfile: importProfile,
+		ExportProfile: exportProfile,
+		Intent:        intent,
+		OptionString:  C.CString(o.OptionString),
+	}
+}
+
+type cThumbnailOptions struct {
+	Size          C.VipsSize
+	Crop          C.VipsInteresting
+	Linear        C.gboolean
+	NoRotate      C.gboolean
+	ImportProfile *C.char
+	ExportProfile *C.char
+	Intent        C.int
+	OptionString  *C.char
+}
+
+func (c *cThumbnailOptions) Free() {
+	if c.ImportProfile != nil {
+		C.free(unsafe.Pointer(c.ImportProfile))
+		c.ImportProfile = nil
+	}
+	if c.ExportProfile != nil {
+		C.free(unsafe.Pointer(c.ExportProfile))
+		c.ExportProfile = nil
+	}
+	if c.OptionString != nil {
+		C.free(unsafe.Pointer(c.OptionString))
+		c.OptionString = nil
+	}
+}
+
+// Thumbnail loads and resizes b in one step, letting the JPEG and WebP loaders shrink while decoding. The result fits
+// within width x height; a zero width or height leaves that dimension unconstrained.
+func Thumbnail(b []byte, width, height int, options *ThumbnailOptions) (*VipsImage, error) {
+	if len(b) == 0 {
+		return nil, ErrThumbnail
+	}
+	if options == nil {
+		options = &ThumbnailOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	width, height = thumbnailSize(width, height)
+	var i *C.struct__VipsImage
+	if C.govips_thumbnail_buffer(unsafe.Pointer(&b[0]), C.size_t(len(b)), &i, C.int(width), C.int(height), cOptions.Size, cOptions.Crop, cOptions.Linear, cOptions.NoRotate, cOptions.ImportProfile, cOptions.ExportProfile, cOptions.Intent, cOptions.OptionString) != 0 {
+		return nil, ErrThumbnail
+	}
+	return newVipsImage(i, b), nil
+}
+
+// ThumbnailImage is Thumbnail for an image that is already loaded, so there is no shrink-on-load.
+func ThumbnailImage(v *VipsImage, width, height int, options *ThumbnailOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &ThumbnailOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	width, height = thumbnailSize(width, height)
+	var i *C.struct__VipsImage
+	if C.govips_thumbnail_image(v.cVipsImage, &i, C.int(width), C.int(height), cOptions.Size, cOptions.Crop, cOptions.Linear, cOptions.NoRotate, cOptions.ImportProfile, cOptions.ExportProfile, cOptions.Intent) != 0 {
+		return nil, ErrThumbnail
+	}
+	return newVipsImage(i, v.goBytes), nil
+}
+
+func thumbnailSize(width, height int) (int, int) {
+	if width <= 0 {
+		width = C.VIPS_MAX_COORD
+	}
+	if height <= 0 {
+		height = C.VIPS_MAX_COORD
+	}
+	return width, height
+}
+
 type SimilarityOptions struct {
 	Scale       float64
 	Angle       float64
@@ -1302,6 +4027,81 @@ func Flatten(v *VipsImage, options *FlattenOptions) (*VipsImage, error) {
 	return newVipsImage(i, v.goBytes), nil
 }
 
+// Autorotate rotates and flips the image upright according to its EXIF orientation and removes the orientation.
+func Autorotate(v *VipsImage) (*VipsImage, error) {
+	var i *C.struct__VipsImage
+	if C.govips_autorot(v.cVipsImage, &i) != 0 {
+		return nil, ErrAutorotate
+	}
+	return newVipsImage(i, v.goBytes), nil
+}
+
+func Rotate(v *VipsImage, angle VipsAngle) (*VipsImage, error) {
+	var i *C.struct__VipsImage
+	if C.govips_rot(v.cVipsImage, &i, angle.toC()) != 0 {
+		return nil, ErrRotate
+	}
+	return newVipsImage(i, v.goBytes), nil
+}
+
+func Flip(v *VipsImage, direction VipsDirection) (*VipsImage, error) {
+	var i *C.struct__VipsImage
+	if C.govips_flip(v.cVipsImage, &i, direction.toC()) != 0 {
+		return nil, ErrFlip
+	}
+	return newVipsImage(i, v.goBytes), nil
+}
+
+type RotateOptions struct {
+	Background []float64
+}
+
+func (o RotateOptions) toC() cRotateOptions {
+	var background *C.struct__VipsArrayDouble
+	if len(o.Background) > 0 {
+		background = newVipsArrayDouble(o.Background)
+	}
+	return cRotateOptions{
+		Background: background,
+	}
+}
+
+type cRotateOptions struct {
+	Background *C.struct__VipsArrayDouble
+}
+
+func (c *cRotateOptions) Free() {
+	if c.Background != nil {
+		vipsArrayDoubleUnref(c.Background)
+		c.Background = nil
+	}
+}
+
+// RotateDegrees rotates clockwise by any angle, growing the image to fit and filling the corners with the
+// background. Multiples of 90 degrees are better done with Rotate, which does not resample.
+func RotateDegrees(v *VipsImage, angle float64, options *RotateOptions) (*VipsImage, error) {
+	if options == nil {
+		options = &RotateOptions{}
+	}
+	cOptions := options.toC()
+	defer cOptions.Free()
+	var i *C.struct__VipsImage
+	if C.govips_rotate(v.cVipsImage, &i, C.double(angle), cOptions.Background) != 0 {
+		return nil, ErrRotate
+	}
+	return newVipsImage(i, v.goBytes), nil
+}
+
+// Cast converts the band format, clipping values that are out of range. With shift, integer values are scaled by the
+// difference in bit depth instead, so that 255 in uchar becomes 65280 in ushort.
+func Cast(v *VipsImage, format VipsBandFormat, shift bool) (*VipsImage, error) {
+	var i *C.struct__VipsImage
+	if C.govips_cast(v.cVipsImage, &i, format.toC(), toGBool(shift)) != 0 {
+		return nil, ErrCast
+	}
+	return newVipsImage(i, v.goBytes), nil
+}
+
 type ColourspaceOptions struct {
 	SourceSpace VipsInterpretation
 }
@@ -1451,6 +4251,58 @@ func NewVSQBSVipsInterpolator() *VipsInterpolate {
 
 // Helpers...
 
+// MapFrames applies op to every frame of a multi-page image, such as an animation decoded with N: ALL_PAGES, and joins
+// the results back into a single strip with the new page height. Single page images are passed to op as is. The other
+// operations treat a multi-page image as one tall strip.
+func MapFrames(v *VipsImage, op func(*VipsImage) (*VipsImage, error)) (*VipsImage, error) {
+	bounds := v.Bounds()
+	pageHeight := int(C.vips_image_get_page_height(v.cVipsImage))
+	if pageHeight <= 0 || pageHeight >= bounds.Dy() {
+		return op(v)
+	}
+	frames := make([]*VipsImage, 0, bounds.Dy()/pageHeight)
+	defer func() {
+		for _, frame := range frames {
+			frame.Free()
+		}
+	}()
+	cFrames := make([]*C.struct__VipsImage, 0, cap(frames))
+	for top := 0; top < bounds.Dy(); top += pageHeight {
+		frame, err := ExtractArea(v, 0, top, bounds.Dx(), pageHeight)
+		if err != nil {
+			return nil, err
+		}
+		result, err := op(frame)
+		frame.Free()
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, result)
+		cFrames = append(cFrames, result.cVipsImage)
+	}
+	var i *C.struct__VipsImage
+	if C.govips_join_frames(&cFrames[0], &i, C.int(len(cFrames))) != 0 {
+		return nil, ErrFrames
+	}
+	return newVipsImage(i, v.goBytes), nil
+}
+
+// frameSize is the size of a single frame, which is the whole image unless it has a page height.
+func frameSize(v *VipsImage) image.Point {
+	return image.Pt(v.Bounds().Dx(), int(C.vips_image_get_page_height(v.cVipsImage)))
+}
+
+func copyImage(v *VipsImage) (*VipsImage, error) {
+	if v.cVipsImage == nil {
+		return nil, ErrCopy
+	}
+	var i *C.struct__VipsImage
+	if C.govips_copy(v.cVipsImage, &i) != 0 {
+		return nil, ErrCopy
+	}
+	return newVipsImage(i, v.goBytes), nil
+}
+
 func newVipsRegion(i *VipsImage, bounds image.Rectangle) *C.VipsRegion {
 	vipsRegion := C.vips_region_new(i.cVipsImage)
 	rect := C.govips_rect_new(C.int(bounds.Min.X), C.int(bounds.Min.Y), C.int(bounds.Dx()), C.int(bounds.Dy()))
@@ -1497,9 +4349,8 @@ func NewNRGBAVipsImage(vi *VipsImage) (*NRGBAVipsImage, error) {
 	if !(bands == 3 || bands == 4) {
 		return nil, fmt.Errorf("Invalid number of bands: %d", bands)
 	}
-	format := C.vips_image_get_format(vi.cVipsImage)
-	if C.vips_image_get_format(vi.cVipsImage) != C.VIPS_FORMAT_UCHAR {
-		return nil, fmt.Errorf("Invalid band format: %v", format)
+	if vi.Format() != VIPS_FORMAT_UCHAR {
+		return nil, fmt.Errorf("Invalid band format: %v", vi.Format())
 	}
 	if vi.Interpretation() != VIPS_INTERPRETATION_sRGB {
 		return nil, fmt.Errorf("Invalid interpretation: %v", vi.Interpretation())
@@ -1543,9 +4394,8 @@ func NewCMYKVipsImage(vi *VipsImage) (*CMYKVipsImage, error) {
 	if bands != 4 {
 		return nil, fmt.Errorf("Invalid number of bands: %d", bands)
 	}
-	format := C.vips_image_get_format(vi.cVipsImage)
-	if C.vips_image_get_format(vi.cVipsImage) != C.VIPS_FORMAT_UCHAR {
-		return nil, fmt.Errorf("Invalid band format: %v", format)
+	if vi.Format() != VIPS_FORMAT_UCHAR {
+		return nil, fmt.Errorf("Invalid band format: %v", vi.Format())
 	}
 	if vi.Interpretation() != VIPS_INTERPRETATION_CMYK {
 		return nil, fmt.Errorf("Invalid interpretation: %v", vi.Interpretation())
@@ -1585,9 +4435,8 @@ func NewGrayVipsImage(vi *VipsImage) (*GrayVipsImage, error) {
 	if bands != 1 {
 		return nil, fmt.Errorf("Invalid number of bands: %d", bands)
 	}
-	format := C.vips_image_get_format(vi.cVipsImage)
-	if C.vips_image_get_format(vi.cVipsImage) != C.VIPS_FORMAT_UCHAR {
-		return nil, fmt.Errorf("Invalid band format: %v", format)
+	if vi.Format() != VIPS_FORMAT_UCHAR {
+		return nil, fmt.Errorf("Invalid band format: %v", vi.Format())
 	}
 	if vi.Interpretation() != VIPS_INTERPRETATION_B_W {
 		return nil, fmt.Errorf("Invalid interpretation: %v", vi.Interpretation())
@@ -1597,6 +4446,215 @@ func NewGrayVipsImage(vi *VipsImage) (*GrayVipsImage, error) {
 	}, nil
 }
 
+type NRGBA64VipsImage struct {
+	*VipsImage
+	cVipsRegion *C.VipsRegion
+	alphaBand   bool
+}
+
+func (v *NRGBA64VipsImage) ColorModel() color.Model {
+	return color.NRGBA64Model
+}
+
+func (v *NRGBA64VipsImage) At(x, y int) color.Color {
+	if v.cVipsRegion == nil {
+		v.cVipsRegion = newVipsRegion(v.VipsImage, v.Bounds())
+		v.alphaBand = C.govips_vips_region_n_elements(v.cVipsRegion) == 4
+	}
+	samples := (*[4]uint16)(unsafe.Pointer(C.govips_region_addr(v.cVipsRegion, C.int(x), C.int(y))))
+	alpha := uint16(0xffff)
+	if v.alphaBand {
+		alpha = samples[3]
+	}
+	return &color.NRGBA64{samples[0], samples[1], samples[2], alpha}
+}
+
+func (v *NRGBA64VipsImage) Free() {
+	if v.cVipsRegion != nil {
+		C.g_object_unref(C.gpointer(v.cVipsRegion))
+		v.cVipsRegion = nil
+	}
+	v.VipsImage.Free()
+}
+
+func NewNRGBA64VipsImage(vi *VipsImage) (*NRGBA64VipsImage, error) {
+	bands := vi.Bands()
+	if !(bands == 3 || bands == 4) {
+		return nil, fmt.Errorf("Invalid number of bands: %d", bands)
+	}
+	if vi.Format() != VIPS_FORMAT_USHORT {
+		return nil, fmt.Errorf("Invalid band format: %v", vi.Format())
+	}
+	if vi.Interpretation() != VIPS_INTERPRETATION_RGB16 {
+		return nil, fmt.Errorf("Invalid interpretation: %v", vi.Interpretation())
+	}
+	return &NRGBA64VipsImage{
+		VipsImage: vi,
+	}, nil
+}
+
+type Gray16VipsImage struct {
+	*VipsImage
+	cVipsRegion *C.VipsRegion
+}
+
+func (v *Gray16VipsImage) ColorModel() color.Model {
+	return color.Gray16Model
+}
+
+func (v *Gray16VipsImage) At(x, y int) color.Color {
+	if v.cVipsRegion == nil {
+		v.cVipsRegion = newVipsRegion(v.VipsImage, v.Bounds())
+	}
+	return &color.Gray16{*(*uint16)(unsafe.Pointer(C.govips_region_addr(v.cVipsRegion, C.int(x), C.int(y))))}
+}
+
+func (v *Gray16VipsImage) Free() {
+	if v.cVipsRegion != nil {
+		C.g_object_unref(C.gpointer(v.cVipsRegion))
+		v.cVipsRegion = nil
+	}
+	v.VipsImage.Free()
+}
+
+func NewGray16VipsImage(vi *VipsImage) (*Gray16VipsImage, error) {
+	bands := vi.Bands()
+	if bands != 1 {
+		return nil, fmt.Errorf("Invalid number of bands: %d", bands)
+	}
+	if vi.Format() != VIPS_FORMAT_USHORT {
+		return nil, fmt.Errorf("Invalid band format: %v", vi.Format())
+	}
+	if vi.Interpretation() != VIPS_INTERPRETATION_GREY16 {
+		return nil, fmt.Errorf("Invalid interpretation: %v", vi.Interpretation())
+	}
+	return &Gray16VipsImage{
+		VipsImage: vi,
+	}, nil
+}
+
+// SampleVipsImage reads samples of any band format as float64. Complex formats return their real component.
+type SampleVipsImage struct {
+	*VipsImage
+	cVipsRegion *C.VipsRegion
+	format      VipsBandFormat
+	size        uintptr
+}
+
+func (v *SampleVipsImage) Sample(x, y, band int) float64 {
+	if v.cVipsRegion == nil {
+		v.cVipsRegion = newVipsRegion(v.VipsImage, v.Bounds())
+	}
+	p := unsafe.Pointer(uintptr(unsafe.Pointer(C.govips_region_addr(v.cVipsRegion, C.int(x), C.int(y)))) + uintptr(band)*v.size)
+	switch v.format {
+	case VIPS_FORMAT_UCHAR:
+		return float64(*(*uint8)(p))
+	case VIPS_FORMAT_CHAR:
+		return float64(*(*int8)(p))
+	case VIPS_FORMAT_USHORT:
+		return float64(*(*uint16)(p))
+	case VIPS_FORMAT_SHORT:
+		return float64(*(*int16)(p))
+	case VIPS_FORMAT_UINT:
+		return float64(*(*uint32)(p))
+	case VIPS_FORMAT_INT:
+		return float64(*(*int32)(p))
+	case VIPS_FORMAT_FLOAT, VIPS_FORMAT_COMPLEX:
+		return float64(*(*float32)(p))
+	default:
+		return *(*float64)(p)
+	}
+}
+
+func (v *SampleVipsImage) Samples(x, y int) []float64 {
+	samples := make([]float64, v.Bands())
+	for band := range samples {
+		samples[band] = v.Sample(x, y, band)
+	}
+	return samples
+}
+
+func (v *SampleVipsImage) Free() {
+	if v.cVipsRegion != nil {
+		C.g_object_unref(C.gpointer(v.cVipsRegion))
+		v.cVipsRegion = nil
+	}
+	v.VipsImage.Free()
+}
+
+func NewSampleVipsImage(vi *VipsImage) (*SampleVipsImage, error) {
+	format := vi.Format()
+	if format == VIPS_FORMAT_NOTSET {
+		return nil, fmt.Errorf("Invalid band format: %v", format)
+	}
+	return &SampleVipsImage{
+		VipsImage: vi,
+		format:    format,
+		size:      uintptr(C.vips_format_sizeof(format.toC())),
+	}, nil
+}
+
+// MutableNRGBAVipsImage is backed by Go memory that libvips reads from directly, so pixels written with Set are seen by
+// operations and encoders. Call Invalidate after changing pixels that libvips has already read.
+type MutableNRGBAVipsImage struct {
+	*VipsImage
+	nrgba *image.NRGBA
+}
+
+func (v *MutableNRGBAVipsImage) ColorModel() color.Model {
+	return color.NRGBAModel
+}
+
+func (v *MutableNRGBAVipsImage) At(x, y int) color.Color {
+	c := v.nrgba.NRGBAAt(x, y)
+	return &c
+}
+
+func (v *MutableNRGBAVipsImage) Set(x, y int, c color.Color) {
+	v.nrgba.Set(x, y, c)
+}
+
+func (v *MutableNRGBAVipsImage) SetNRGBA(x, y int, c color.NRGBA) {
+	v.nrgba.SetNRGBA(x, y, c)
+}
+
+// NRGBA shares the pixels of the image, which lets the draw packages use their NRGBA fast paths.
+func (v *MutableNRGBAVipsImage) NRGBA() *image.NRGBA {
+	return v.nrgba
+}
+
+// Invalidate drops pixels and operations libvips has cached for the image.
+func (v *MutableNRGBAVipsImage) Invalidate() {
+	if v.cVipsImage != nil {
+		C.vips_image_invalidate_all(v.cVipsImage)
+	}
+}
+
+func NewMutableNRGBAVipsImage(width, height int) (*MutableNRGBAVipsImage, error) {
+	return newMutableNRGBAVipsImage(image.NewNRGBA(image.Rect(0, 0, width, height)))
+}
+
+// ToMutableNRGBA copies the image into a new mutable image.
+func (v *VipsImage) ToMutableNRGBA() (*MutableNRGBAVipsImage, error) {
+	nrgba, err := v.ToNRGBA()
+	if err != nil {
+		return nil, err
+	}
+	return newMutableNRGBAVipsImage(nrgba)
+}
+
+func newMutableNRGBAVipsImage(nrgba *image.NRGBA) (*MutableNRGBAVipsImage, error) {
+	bounds := nrgba.Bounds()
+	vi, err := newFromMemory(nrgba.Pix, bounds.Dx(), bounds.Dy(), 4, VIPS_FORMAT_UCHAR, VIPS_INTERPRETATION_sRGB)
+	if err != nil {
+		return nil, err
+	}
+	return &MutableNRGBAVipsImage{
+		VipsImage: vi,
+		nrgba:     nrgba,
+	}, nil
+}
+
 // Utilities...
 
 func newVipsArrayInt(slice []int) *C.struct__VipsArrayInt {
@@ -1607,6 +4665,15 @@ func newVipsArrayDouble(slice []float64) *C.struct__VipsArrayDouble {
 	return C.vips_array_double_new((*C.double)(unsafe.Pointer(&slice[0])), C.int(len(slice)))
 }
 
+func newCIntArray(slice []int) *C.int {
+	array := (*C.int)(C.malloc(C.size_t(len(slice)) * C.size_t(unsafe.Sizeof(C.int(0)))))
+	cSlice := (*[1 << 28]C.int)(unsafe.Pointer(array))[:len(slice):len(slice)]
+	for i, value := range slice {
+		cSlice[i] = C.int(value)
+	}
+	return array
+}
+
 func vipsArrayDoubleUnref(i *C.struct__VipsArrayDouble) {
 	vipsAreaUnref((*C.struct__VipsArea)(unsafe.Pointer(i)))
 }
@@ -1619,6 +4686,21 @@ func vipsAreaUnref(i *C.struct__VipsArea) {
 	C.vips_area_unref(i)
 }
 
+var nativeLittleEndian = func() bool {
+	i := uint16(1)
+	return (*[2]byte)(unsafe.Pointer(&i))[0] == 1
+}()
+
+func clampInt(value, min, max int) int {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
+
 func toGBool(b bool) C.gboolean {
 	if b {
 		return C.gboolean(1)
@@ -1,10 +1,217 @@
 #include <stdbool.h>
 #include <stdlib.h>
+#include <string.h>
 #include <vips/vips.h>
 
-int govips_gifload_buffer(void *input, size_t length, VipsImage **output, gint page, VipsAccess access, gboolean disc) {
+extern gint64 govipsSourceRead(int id, void *buffer, gint64 length);
+extern gint64 govipsSourceSeek(int id, gint64 offset, int whence);
+extern gint64 govipsTargetWrite(int id, void *data, gint64 length);
+extern void govipsStreamRelease(int id);
+
+gint64 govips_source_read(VipsSourceCustom *source, void *buffer, gint64 length, gpointer user_data) {
+  return govipsSourceRead(GPOINTER_TO_INT(user_data), buffer, length);
+}
+
+gint64 govips_source_seek(VipsSourceCustom *source, gint64 offset, int whence, gpointer user_data) {
+  return govipsSourceSeek(GPOINTER_TO_INT(user_data), offset, whence);
+}
+
+void govips_stream_release(gpointer user_data, GClosure *closure) {
+  govipsStreamRelease(GPOINTER_TO_INT(user_data));
+}
+
+VipsSource *govips_source_new(int id, gboolean seekable) {
+  VipsSourceCustom *source = vips_source_custom_new();
+  g_signal_connect_data(source, "read", G_CALLBACK(govips_source_read), GINT_TO_POINTER(id), govips_stream_release, 0);
+  if (seekable) {
+    g_signal_connect(source, "seek", G_CALLBACK(govips_source_seek), GINT_TO_POINTER(id));
+  }
+  return VIPS_SOURCE(source);
+}
+
+int govips_copy(VipsImage *in, VipsImage **out) {
+  return vips_copy(in, out, NULL);
+}
+
+int govips_copy_animation(VipsImage *in, VipsImage **out, int *delay, int n_delay, gint loop) {
+  if (vips_copy(in, out, NULL) != 0) {
+    return -1;
+  }
+  if (delay != NULL && n_delay > 0) {
+    vips_image_set_array_int(*out, "delay", delay, n_delay);
+  }
+  if (loop >= 0) {
+    vips_image_set_int(*out, "loop", loop);
+  }
+  return 0;
+}
+
+int govips_gifload(const char *filename, VipsImage **output, gint page, gint n, VipsAccess access, gboolean disc) {
+  return vips_gifload(filename, output,
+    "page", page,
+    "n", n,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
+int govips_gifload_buffer(void *input, size_t length, VipsImage **output, gint page, gint n, VipsAccess access, gboolean disc) {
   return vips_gifload_buffer(input, length, output,
     "page", page,
+    "n", n,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
+gint64 govips_target_write(VipsTargetCustom *target, const void *data, gint64 length, gpointer user_data) {
+  return govipsTargetWrite(GPOINTER_TO_INT(user_data), (void *) data, length);
+}
+
+VipsTarget *govips_target_new(int id) {
+  VipsTargetCustom *target = vips_target_custom_new();
+  g_signal_connect_data(target, "write", G_CALLBACK(govips_target_write), GINT_TO_POINTER(id), govips_stream_release, 0);
+  return VIPS_TARGET(target);
+}
+
+int govips_gifload_source(VipsSource *source, VipsImage **output, gint page, gint n, VipsAccess access, gboolean disc) {
+  return vips_gifload_source(source, output,
+    "page", page,
+    "n", n,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
+int govips_gifsave_buffer(VipsImage *input, void **output, size_t *length, gdouble dither, gint effort, gint bitdepth, gint loop) {
+  VipsImage *copy;
+  int result;
+  if (govips_copy_animation(input, &copy, NULL, 0, loop) != 0) {
+    return -1;
+  }
+  result = vips_gifsave_buffer(copy, output, length,
+    "dither", dither,
+    "effort", effort,
+    "bitdepth", bitdepth,
+    NULL);
+  g_object_unref(copy);
+  return result;
+}
+
+int govips_gifsave_target(VipsImage *input, VipsTarget *target, gdouble dither, gint effort, gint bitdepth, gint loop) {
+  VipsImage *copy;
+  int result;
+  if (govips_copy_animation(input, &copy, NULL, 0, loop) != 0) {
+    return -1;
+  }
+  result = vips_gifsave_target(copy, target,
+    "dither", dither,
+    "effort", effort,
+    "bitdepth", bitdepth,
+    NULL);
+  g_object_unref(copy);
+  return result;
+}
+
+int govips_heifload(const char *filename, VipsImage **output, gint page, gint n, gboolean thumbnail, VipsAccess access, gboolean disc) {
+  return vips_heifload(filename, output,
+    "page", page,
+    "n", n,
+    "thumbnail", thumbnail,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
+int govips_heifload_buffer(void *input, size_t length, VipsImage **output, gint page, gint n, gboolean thumbnail, VipsAccess access, gboolean disc) {
+  return vips_heifload_buffer(input, length, output,
+    "page", page,
+    "n", n,
+    "thumbnail", thumbnail,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
+int govips_heifload_source(VipsSource *source, VipsImage **output, gint page, gint n, gboolean thumbnail, VipsAccess access, gboolean disc) {
+  return vips_heifload_source(source, output,
+    "page", page,
+    "n", n,
+    "thumbnail", thumbnail,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
+int govips_heifsave_buffer(VipsImage *input, void **output, size_t *length, gint Q, gboolean lossless, VipsForeignHeifCompression compression, gint effort, VipsForeignSubsample subsample_mode) {
+  return vips_heifsave_buffer(input, output, length,
+    "Q", Q,
+    "lossless", lossless,
+    "compression", compression,
+    "effort", effort,
+    "subsample_mode", subsample_mode,
+    NULL);
+}
+
+int govips_heifsave_target(VipsImage *input, VipsTarget *target, gint Q, gboolean lossless, VipsForeignHeifCompression compression, gint effort, VipsForeignSubsample subsample_mode) {
+  return vips_heifsave_target(input, target,
+    "Q", Q,
+    "lossless", lossless,
+    "compression", compression,
+    "effort", effort,
+    "subsample_mode", subsample_mode,
+    NULL);
+}
+
+int govips_jp2kload(const char *filename, VipsImage **output, gint page, VipsAccess access, gboolean disc) {
+  return vips_jp2kload(filename, output,
+    "page", page,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
+int govips_jp2kload_buffer(void *input, size_t length, VipsImage **output, gint page, VipsAccess access, gboolean disc) {
+  return vips_jp2kload_buffer(input, length, output,
+    "page", page,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
+int govips_jp2kload_source(VipsSource *source, VipsImage **output, gint page, VipsAccess access, gboolean disc) {
+  return vips_jp2kload_source(source, output,
+    "page", page,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
+int govips_jp2ksave_buffer(VipsImage *input, void **output, size_t *length, gint Q, gboolean lossless, gint tile_width, gint tile_height, VipsForeignSubsample subsample_mode) {
+  return vips_jp2ksave_buffer(input, output, length,
+    "Q", Q,
+    "lossless", lossless,
+    "tile_width", tile_width,
+    "tile_height", tile_height,
+    "subsample_mode", subsample_mode,
+    NULL);
+}
+
+int govips_jp2ksave_target(VipsImage *input, VipsTarget *target, gint Q, gboolean lossless, gint tile_width, gint tile_height, VipsForeignSubsample subsample_mode) {
+  return vips_jp2ksave_target(input, target,
+    "Q", Q,
+    "lossless", lossless,
+    "tile_width", tile_width,
+    "tile_height", tile_height,
+    "subsample_mode", subsample_mode,
+    NULL);
+}
+
+int govips_jpegload(const char *filename, VipsImage **output, gint shrink, gboolean fail, gboolean autorotate, VipsAccess access, gboolean disc) {
+  return vips_jpegload(filename, output,
+    "shrink", shrink,
+    "fail", fail,
+    "autorotate", autorotate,
     "access", access,
     "disc", disc,
     NULL);
@@ -20,6 +227,16 @@ int govips_jpegload_buffer(void *input, size_t length, VipsImage **output, gint
     NULL);
 }
 
+int govips_jpegload_source(VipsSource *source, VipsImage **output, gint shrink, gboolean fail, gboolean autorotate, VipsAccess access, gboolean disc) {
+  return vips_jpegload_source(source, output,
+    "shrink", shrink,
+    "fail", fail,
+    "autorotate", autorotate,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
 int govips_jpegsave_buffer(VipsImage *input, void **output, size_t *length, gint Q, const char *profile, gboolean optimize_coding, gboolean interlace, gboolean strip, gboolean nosubsample, gboolean trellis_quant, gboolean overshoot_deringing, gboolean optimize_scans, gint quant_table) {
   return vips_jpegsave_buffer(input, output, length,
     "Q", Q,
@@ -35,8 +252,8 @@ int govips_jpegsave_buffer(VipsImage *input, void **output, size_t *length, gint
     NULL);
 }
 
-int govips_jpegsave(VipsImage *input, const char *filename, gint Q, const char *profile, gboolean optimize_coding, gboolean interlace, gboolean strip, gboolean nosubsample, gboolean trellis_quant, gboolean overshoot_deringing, gboolean optimize_scans, gint quant_table) {
-  return vips_jpegsave(input, filename,
+int govips_jpegsave_target(VipsImage *input, VipsTarget *target, gint Q, const char *profile, gboolean optimize_coding, gboolean interlace, gboolean strip, gboolean nosubsample, gboolean trellis_quant, gboolean overshoot_deringing, gboolean optimize_scans, gint quant_table) {
+  return vips_jpegsave_target(input, target,
     "Q", Q,
     "profile", profile,
     "optimize_coding", optimize_coding,
@@ -50,11 +267,135 @@ int govips_jpegsave(VipsImage *input, const char *filename, gint Q, const char *
     NULL);
 }
 
-int govips_magickload_buffer(void *input, size_t length, VipsImage **output, gboolean all_frames, const char *density, gint page, VipsAccess access, gboolean disc) {
+int govips_jxlload(const char *filename, VipsImage **output, VipsAccess access, gboolean disc) {
+  return vips_jxlload(filename, output,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
+int govips_jxlload_buffer(void *input, size_t length, VipsImage **output, VipsAccess access, gboolean disc) {
+  return vips_jxlload_buffer(input, length, output,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
+int govips_jxlload_source(VipsSource *source, VipsImage **output, VipsAccess access, gboolean disc) {
+  return vips_jxlload_source(source, output,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
+int govips_jxlsave_buffer(VipsImage *input, void **output, size_t *length, gdouble distance, gint Q, gint effort, gboolean lossless, gint tier) {
+  if (distance > 0) {
+    return vips_jxlsave_buffer(input, output, length,
+      "distance", distance,
+      "effort", effort,
+      "lossless", lossless,
+      "tier", tier,
+      NULL);
+  }
+  return vips_jxlsave_buffer(input, output, length,
+    "Q", Q,
+    "effort", effort,
+    "lossless", lossless,
+    "tier", tier,
+    NULL);
+}
+
+int govips_jxlsave_target(VipsImage *input, VipsTarget *target, gdouble distance, gint Q, gint effort, gboolean lossless, gint tier) {
+  if (distance > 0) {
+    return vips_jxlsave_target(input, target,
+      "distance", distance,
+      "effort", effort,
+      "lossless", lossless,
+      "tier", tier,
+      NULL);
+  }
+  return vips_jxlsave_target(input, target,
+    "Q", Q,
+    "effort", effort,
+    "lossless", lossless,
+    "tier", tier,
+    NULL);
+}
+
+int govips_magickload(const char *filename, VipsImage **output, gboolean all_frames, const char *density, gint page, gint n, VipsAccess access, gboolean disc) {
+  return vips_magickload(filename, output,
+    "all_frames", all_frames,
+    "density", density,
+    "page", page,
+    "n", n,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
+int govips_magickload_buffer(void *input, size_t length, VipsImage **output, gboolean all_frames, const char *density, gint page, gint n, VipsAccess access, gboolean disc) {
   return vips_magickload_buffer(input, length, output,
     "all_frames", all_frames,
     "density", density,
     "page", page,
+    "n", n,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
+int govips_magickload_source(VipsSource *source, VipsImage **output, gboolean all_frames, const char *density, gint page, gint n, VipsAccess access, gboolean disc) {
+  return vips_magickload_source(source, output,
+    "all_frames", all_frames,
+    "density", density,
+    "page", page,
+    "n", n,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
+int govips_pdfload(const char *filename, VipsImage **output, gint page, gint n, gdouble dpi, gdouble scale, VipsArrayDouble *background, const char *password, VipsAccess access, gboolean disc) {
+  return vips_pdfload(filename, output,
+    "page", page,
+    "n", n,
+    "dpi", dpi,
+    "scale", scale,
+    "background", background,
+    "password", password,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
+int govips_pdfload_buffer(void *input, size_t length, VipsImage **output, gint page, gint n, gdouble dpi, gdouble scale, VipsArrayDouble *background, const char *password, VipsAccess access, gboolean disc) {
+  return vips_pdfload_buffer(input, length, output,
+    "page", page,
+    "n", n,
+    "dpi", dpi,
+    "scale", scale,
+    "background", background,
+    "password", password,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
+int govips_pdfload_source(VipsSource *source, VipsImage **output, gint page, gint n, gdouble dpi, gdouble scale, VipsArrayDouble *background, const char *password, VipsAccess access, gboolean disc) {
+  return vips_pdfload_source(source, output,
+    "page", page,
+    "n", n,
+    "dpi", dpi,
+    "scale", scale,
+    "background", background,
+    "password", password,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
+int govips_pngload(const char *filename, VipsImage **output, VipsAccess access, gboolean disc) {
+  return vips_pngload(filename, output,
     "access", access,
     "disc", disc,
     NULL);
@@ -67,6 +408,13 @@ int govips_pngload_buffer(void *input, size_t length, VipsImage **output, VipsAc
     NULL);
 }
 
+int govips_pngload_source(VipsSource *source, VipsImage **output, VipsAccess access, gboolean disc) {
+  return vips_pngload_source(source, output,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
 int govips_pngsave_buffer(VipsImage *input, void **output, size_t *length, gint compression, gboolean interlace, const char *profile, VipsForeignPngFilter filter) {
   return vips_pngsave_buffer(input, output, length,
     "compression", compression,
@@ -76,8 +424,8 @@ int govips_pngsave_buffer(VipsImage *input, void **output, size_t *length, gint
     NULL);
 }
 
-int govips_pngsave(VipsImage *input, const char *filename, gint compression, gboolean interlace, const char *profile, VipsForeignPngFilter filter) {
-  return vips_pngsave(input, filename,
+int govips_pngsave_target(VipsImage *input, VipsTarget *target, gint compression, gboolean interlace, const char *profile, VipsForeignPngFilter filter) {
+  return vips_pngsave_target(input, target,
     "compression", compression,
     "interlace", interlace,
     "profile", profile,
@@ -85,36 +433,180 @@ int govips_pngsave(VipsImage *input, const char *filename, gint compression, gbo
     NULL);
 }
 
-int govips_webpload_buffer(void *input, size_t length, VipsImage **output, gint shrink, VipsAccess access, gboolean disc) {
+int govips_svgload(const char *filename, VipsImage **output, gdouble dpi, gdouble scale, gboolean unlimited, VipsAccess access, gboolean disc) {
+  return vips_svgload(filename, output,
+    "dpi", dpi,
+    "scale", scale,
+    "unlimited", unlimited,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
+int govips_svgload_buffer(void *input, size_t length, VipsImage **output, gdouble dpi, gdouble scale, gboolean unlimited, VipsAccess access, gboolean disc) {
+  return vips_svgload_buffer(input, length, output,
+    "dpi", dpi,
+    "scale", scale,
+    "unlimited", unlimited,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
+int govips_svgload_source(VipsSource *source, VipsImage **output, gdouble dpi, gdouble scale, gboolean unlimited, VipsAccess access, gboolean disc) {
+  return vips_svgload_source(source, output,
+    "dpi", dpi,
+    "scale", scale,
+    "unlimited", unlimited,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
+int govips_tiffload(const char *filename, VipsImage **output, gint page, gint n, gboolean autorotate, VipsAccess access, gboolean disc) {
+  return vips_tiffload(filename, output,
+    "page", page,
+    "n", n,
+    "autorotate", autorotate,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
+int govips_tiffload_buffer(void *input, size_t length, VipsImage **output, gint page, gint n, gboolean autorotate, VipsAccess access, gboolean disc) {
+  return vips_tiffload_buffer(input, length, output,
+    "page", page,
+    "n", n,
+    "autorotate", autorotate,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
+int govips_tiffload_source(VipsSource *source, VipsImage **output, gint page, gint n, gboolean autorotate, VipsAccess access, gboolean disc) {
+  return vips_tiffload_source(source, output,
+    "page", page,
+    "n", n,
+    "autorotate", autorotate,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
+int govips_tiffsave_buffer(VipsImage *input, void **output, size_t *length, VipsForeignTiffCompression compression, gint Q, VipsForeignTiffPredictor predictor, const char *profile, gboolean tile, gint tile_width, gint tile_height, gboolean pyramid, gboolean bigtiff) {
+  return vips_tiffsave_buffer(input, output, length,
+    "compression", compression,
+    "Q", Q,
+    "predictor", predictor,
+    "profile", profile,
+    "tile", tile,
+    "tile_width", tile_width,
+    "tile_height", tile_height,
+    "pyramid", pyramid,
+    "bigtiff", bigtiff,
+    NULL);
+}
+
+int govips_tiffsave_target(VipsImage *input, VipsTarget *target, VipsForeignTiffCompression compression, gint Q, VipsForeignTiffPredictor predictor, const char *profile, gboolean tile, gint tile_width, gint tile_height, gboolean pyramid, gboolean bigtiff) {
+  return vips_tiffsave_target(input, target,
+    "compression", compression,
+    "Q", Q,
+    "predictor", predictor,
+    "profile", profile,
+    "tile", tile,
+    "tile_width", tile_width,
+    "tile_height", tile_height,
+    "pyramid", pyramid,
+    "bigtiff", bigtiff,
+    NULL);
+}
+
+int govips_webpload(const char *filename, VipsImage **output, gint shrink, gint page, gint n, VipsAccess access, gboolean disc) {
+  return vips_webpload(filename, output,
+    "shrink", shrink,
+    "page", page,
+    "n", n,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
+int govips_webpload_buffer(void *input, size_t length, VipsImage **output, gint shrink, gint page, gint n, VipsAccess access, gboolean disc) {
   return vips_webpload_buffer(input, length, output,
     "shrink", shrink,
+    "page", page,
+    "n", n,
+    "access", access,
+    "disc", disc,
+    NULL);
+}
+
+int govips_webpload_source(VipsSource *source, VipsImage **output, gint shrink, gint page, gint n, VipsAccess access, gboolean disc) {
+  return vips_webpload_source(source, output,
+    "shrink", shrink,
+    "page", page,
+    "n", n,
     "access", access,
     "disc", disc,
     NULL);
 }
 
-int govips_webpsave_buffer(VipsImage *input, void **output, size_t *length, gint Q, gboolean lossless, VipsForeignWebpPreset preset, gboolean smart_subsample, gboolean near_lossless, gint alpha_q) {
-  return vips_webpsave_buffer(input, output, length,
+int govips_webpsave_buffer(VipsImage *input, void **output, size_t *length, gint Q, gboolean lossless, VipsForeignWebpPreset preset, gboolean smart_subsample, gboolean near_lossless, gint alpha_q, gint kmin, gint kmax, gboolean min_size, gboolean strip, int *delay, int n_delay, gint loop) {
+  VipsImage *copy;
+  int result;
+  if (govips_copy_animation(input, &copy, delay, n_delay, loop) != 0) {
+    return -1;
+  }
+  result = vips_webpsave_buffer(copy, output, length,
     "Q", Q,
     "lossless", lossless,
     "preset", preset,
     "smart_subsample", smart_subsample,
     "near_lossless", near_lossless,
     "alpha_q", alpha_q,
-    "strip", 1,
+    "kmin", kmin,
+    "kmax", kmax,
+    "min_size", min_size,
+    "strip", strip,
     NULL);
+  g_object_unref(copy);
+  return result;
 }
 
-int govips_webpsave(VipsImage *input, const char *filename, gint Q, gboolean lossless, VipsForeignWebpPreset preset, gboolean smart_subsample, gboolean near_lossless, gint alpha_q) {
-  return vips_webpsave(input, filename,
+int govips_webpsave_target(VipsImage *input, VipsTarget *target, gint Q, gboolean lossless, VipsForeignWebpPreset preset, gboolean smart_subsample, gboolean near_lossless, gint alpha_q, gint kmin, gint kmax, gboolean min_size, gboolean strip, int *delay, int n_delay, gint loop) {
+  VipsImage *copy;
+  int result;
+  if (govips_copy_animation(input, &copy, delay, n_delay, loop) != 0) {
+    return -1;
+  }
+  result = vips_webpsave_target(copy, target,
     "Q", Q,
     "lossless", lossless,
     "preset", preset,
     "smart_subsample", smart_subsample,
     "near_lossless", near_lossless,
     "alpha_q", alpha_q,
-    "strip", 1,
+    "kmin", kmin,
+    "kmax", kmax,
+    "min_size", min_size,
+    "strip", strip,
     NULL);
+  g_object_unref(copy);
+  return result;
+}
+
+int govips_join_frames(VipsImage **in, VipsImage **out, int n) {
+  VipsImage *joined;
+  if (vips_arrayjoin(in, &joined, n, "across", 1, NULL) != 0) {
+    return -1;
+  }
+  if (vips_copy(joined, out, NULL) != 0) {
+    g_object_unref(joined);
+    return -1;
+  }
+  g_object_unref(joined);
+  vips_image_set_int(*out, VIPS_META_PAGE_HEIGHT, in[0]->Ysize);
+  return 0;
 }
 
 int govips_embed(VipsImage *in, VipsImage **out, int x, int y, int width, int height, VipsExtend extend, VipsArrayDouble *background) {
@@ -128,6 +620,15 @@ int govips_extract_area(VipsImage *in, VipsImage **out, int left, int top, int w
   return vips_extract_area(in, out, left, top, width, height, NULL);
 }
 
+int govips_smartcrop(VipsImage *in, VipsImage **out, int width, int height, VipsInteresting interesting, int *left, int *top) {
+  if (vips_smartcrop(in, out, width, height, "interesting", interesting, NULL) != 0) {
+    return -1;
+  }
+  *left = -vips_image_get_xoffset(*out);
+  *top = -vips_image_get_yoffset(*out);
+  return 0;
+}
+
 int govips_shrink(VipsImage *in, VipsImage **out, double xshrink, double yshrink) {
   return vips_shrink(in, out, xshrink, yshrink, NULL);
 }
@@ -156,6 +657,56 @@ int govips_resize(VipsImage *in, VipsImage **out, double scale, double vscale, V
   return vips_resize(in, out, scale, "vscale", vscale, "kernel", kernel, NULL);
 }
 
+int govips_thumbnail_buffer(void *input, size_t length, VipsImage **out, int width, int height, VipsSize size, VipsInteresting crop, gboolean linear, gboolean no_rotate, const char *import_profile, const char *export_profile, int intent, const char *option_string) {
+  if (intent < 0) {
+    return vips_thumbnail_buffer(input, length, out, width,
+      "height", height,
+      "size", size,
+      "crop", crop,
+      "linear", linear,
+      "no_rotate", no_rotate,
+      "import_profile", import_profile,
+      "export_profile", export_profile,
+      "option_string", option_string,
+      NULL);
+  }
+  return vips_thumbnail_buffer(input, length, out, width,
+    "height", height,
+    "size", size,
+    "crop", crop,
+    "linear", linear,
+    "no_rotate", no_rotate,
+    "import_profile", import_profile,
+    "export_profile", export_profile,
+    "intent", intent,
+    "option_string", option_string,
+    NULL);
+}
+
+int govips_thumbnail_image(VipsImage *in, VipsImage **out, int width, int height, VipsSize size, VipsInteresting crop, gboolean linear, gboolean no_rotate, const char *import_profile, const char *export_profile, int intent) {
+  if (intent < 0) {
+    return vips_thumbnail_image(in, out, width,
+      "height", height,
+      "size", size,
+      "crop", crop,
+      "linear", linear,
+      "no_rotate", no_rotate,
+      "import_profile", import_profile,
+      "export_profile", export_profile,
+      NULL);
+  }
+  return vips_thumbnail_image(in, out, width,
+    "height", height,
+    "size", size,
+    "crop", crop,
+    "linear", linear,
+    "no_rotate", no_rotate,
+    "import_profile", import_profile,
+    "export_profile", export_profile,
+    "intent", intent,
+    NULL);
+}
+
 int govips_similarity(VipsImage *in, VipsImage **out, gdouble scale, gdouble angle, VipsInterpolate *interpolate, gdouble idx, gdouble idy, gdouble odx, gdouble ody) {
   return vips_similarity(in, out, "scale", scale, "angle", angle, "interpolate", interpolate, "idx", idx, "idy", idy, "odx", odx, "ody", ody, NULL);
 }
@@ -182,6 +733,29 @@ int govips_flatten(VipsImage *in, VipsImage **out, VipsArrayDouble *background,
   return vips_flatten(in, out, "background", background, "max_alpha", max_alpha, NULL);
 }
 
+int govips_autorot(VipsImage *in, VipsImage **out) {
+  return vips_autorot(in, out, NULL);
+}
+
+int govips_rot(VipsImage *in, VipsImage **out, VipsAngle angle) {
+  return vips_rot(in, out, angle, NULL);
+}
+
+int govips_flip(VipsImage *in, VipsImage **out, VipsDirection direction) {
+  return vips_flip(in, out, direction, NULL);
+}
+
+int govips_rotate(VipsImage *in, VipsImage **out, double angle, VipsArrayDouble *background) {
+  if (background == NULL) {
+    return vips_rotate(in, out, angle, NULL);
+  }
+  return vips_rotate(in, out, angle, "background", background, NULL);
+}
+
+int govips_cast(VipsImage *in, VipsImage **out, VipsBandFormat format, gboolean shift) {
+  return vips_cast(in, out, format, "shift", shift, NULL);
+}
+
 int govips_colourspace(VipsImage *in, VipsImage **out, VipsInterpretation space, VipsInterpretation source_space) {
   return vips_colourspace(in, out, space, "source_space", source_space, NULL);
 }
@@ -190,6 +764,149 @@ int govips_icc_transform(VipsImage *in, VipsImage **out, const char *output_prof
   return vips_icc_transform(in, out, output_profile, "input_profile", input_profile, "intent", intent, "depth", depth, "embedded", embedded, NULL);
 }
 
+int govips_get_int(VipsImage *in, const char *name, int fallback) {
+  int out;
+  if (vips_image_get_typeof(in, name) == 0 || vips_image_get_int(in, name, &out) != 0) {
+    return fallback;
+  }
+  return out;
+}
+
+int *govips_get_array_int(VipsImage *in, const char *name, int *n) {
+  int *out;
+  if (vips_image_get_typeof(in, name) == 0 || vips_image_get_array_int(in, name, &out, n) != 0) {
+    *n = 0;
+    return NULL;
+  }
+  return out;
+}
+
+const char *govips_get_string(VipsImage *in, const char *name) {
+  const char *out;
+  if (vips_image_get_typeof(in, name) == 0 || vips_image_get_string(in, name, &out) != 0) {
+    return NULL;
+  }
+  return out;
+}
+
+const void *govips_get_blob(VipsImage *in, const char *name, size_t *length) {
+  const void *out;
+  if (vips_image_get_typeof(in, name) == 0 || vips_image_get_blob(in, name, &out, length) != 0) {
+    *length = 0;
+    return NULL;
+  }
+  return out;
+}
+
+void govips_memory_release(gpointer user_data) {
+  govipsStreamRelease(GPOINTER_TO_INT(user_data));
+}
+
+VipsImage *govips_image_new_from_memory(const void *data, size_t size, int width, int height, int bands, VipsBandFormat format, VipsInterpretation interpretation, int id) {
+  VipsImage *image = vips_image_new_from_memory(data, size, width, height, bands, format);
+  if (image == NULL) {
+    govipsStreamRelease(id);
+    return NULL;
+  }
+  image->Type = interpretation;
+  g_object_set_data_full(G_OBJECT(image), "govips-memory", GINT_TO_POINTER(id), govips_memory_release);
+  return image;
+}
+
+int govips_unpremultiply(VipsImage *in, VipsImage **out) {
+  VipsImage *unpremultiplied;
+  int result;
+  if (vips_unpremultiply(in, &unpremultiplied, NULL) != 0) {
+    return -1;
+  }
+  result = vips_cast(unpremultiplied, out, VIPS_FORMAT_UCHAR, NULL);
+  g_object_unref(unpremultiplied);
+  return result;
+}
+
+int govips_ycbcr_to_srgb(VipsImage *y, VipsImage *cb, VipsImage *cr, VipsImage **out, int xfac, int yfac) {
+  VipsImage *context = vips_image_new();
+  VipsImage **t = (VipsImage **) vips_object_local_array(VIPS_OBJECT(context), 9);
+  double a[3] = {1, 1, 1};
+  double b[3] = {-179.456 + 0.5, 135.458816 + 0.5, -226.816 + 0.5};
+  int result = -1;
+  t[0] = vips_image_new_matrixv(3, 3,
+    1.0, 0.0, 1.402,
+    1.0, -0.344136, -0.714136,
+    1.0, 1.772, 0.0);
+  if (vips_zoom(cb, &t[1], xfac, yfac, NULL) == 0 &&
+      vips_zoom(cr, &t[2], xfac, yfac, NULL) == 0 &&
+      vips_extract_area(t[1], &t[3], 0, 0, y->Xsize, y->Ysize, NULL) == 0 &&
+      vips_extract_area(t[2], &t[4], 0, 0, y->Xsize, y->Ysize, NULL) == 0 &&
+      vips_bandjoin3(y, t[3], t[4], &t[5], NULL) == 0 &&
+      vips_recomb(t[5], &t[6], t[0], NULL) == 0 &&
+      vips_linear(t[6], &t[7], a, b, 3, NULL) == 0 &&
+      vips_cast(t[7], &t[8], VIPS_FORMAT_UCHAR, NULL) == 0) {
+    result = vips_copy(t[8], out, "interpretation", VIPS_INTERPRETATION_sRGB, NULL);
+  }
+  g_object_unref(context);
+  return result;
+}
+
+int govips_write_area(VipsRegion *region, VipsRect *area, void *a) {
+  VipsImage *image = region->im;
+  size_t line = VIPS_IMAGE_SIZEOF_LINE(image);
+  int y;
+  for (y = 0; y < area->height; y++) {
+    memcpy((VipsPel *) a + (size_t) (area->top + y) * line, VIPS_REGION_ADDR(region, area->left, area->top + y), line);
+  }
+  return 0;
+}
+
+int govips_to_memory(VipsImage *in, int left, int top, int width, int height, VipsInterpretation space, VipsBandFormat format, int bands, gboolean premultiply, void *out, size_t size) {
+  VipsImage *context = vips_image_new();
+  VipsImage **t = (VipsImage **) vips_object_local_array(VIPS_OBJECT(context), 6);
+  VipsImage *image;
+  int result = -1;
+  double max_alpha = format == VIPS_FORMAT_USHORT ? 65535 : 255;
+  if (vips_extract_area(in, &t[0], left, top, width, height, NULL) != 0) {
+    goto done;
+  }
+  image = t[0];
+  if (vips_image_guess_interpretation(image) != space) {
+    if (vips_colourspace(image, &t[1], space, NULL) != 0) {
+      goto done;
+    }
+    image = t[1];
+  }
+  if (image->Bands > bands) {
+    if (vips_extract_band(image, &t[2], 0, "n", bands, NULL) != 0) {
+      goto done;
+    }
+    image = t[2];
+  } else if (image->Bands < bands) {
+    if (vips_bandjoin_const1(image, &t[2], max_alpha, NULL) != 0) {
+      goto done;
+    }
+    image = t[2];
+  }
+  if (premultiply) {
+    if (vips_premultiply(image, &t[3], "max_alpha", max_alpha, NULL) != 0) {
+      goto done;
+    }
+    image = t[3];
+  }
+  if (image->BandFmt != format) {
+    if (vips_cast(image, &t[4], format, NULL) != 0) {
+      goto done;
+    }
+    image = t[4];
+  }
+  if (VIPS_IMAGE_SIZEOF_IMAGE(image) != size) {
+    vips_error("govips", "invalid buffer size");
+    goto done;
+  }
+  result = vips_sink_disc(image, govips_write_area, out);
+done:
+  g_object_unref(context);
+  return result;
+}
+
 VipsRect govips_rect_new(int left, int top, int width, int height) {
   VipsRect r = { .left = left, .top = top, .width = width, .height = height };
   return r;